@@ -0,0 +1,18 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mach-composer/mach-composer-plugin-sentry/internal"
+)
+
+// The command line tool inspects the Sentry configuration of a mach-composer
+// config file. It is a separate binary, since mach-composer starts the plugin
+// binary itself.
+func main() {
+	if err := internal.RunCLI(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
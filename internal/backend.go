@@ -0,0 +1,63 @@
+package internal
+
+import "fmt"
+
+const (
+	providerSourceLabd     = "labd/sentry"
+	providerSourceJianyuan = "jianyuan/sentry"
+)
+
+// providerBackend describes how the plugin configuration maps onto the
+// resources and attributes of one of the Sentry Terraform providers.
+type providerBackend struct {
+	// source is the registry address of the provider.
+	source string
+	// defaultVersion is used when no provider version is configured in
+	// mach-composer.
+	defaultVersion string
+	// resourcesTemplate renders the resources of a managed component.
+	resourcesTemplate string
+	// dsnAttribute and secretAttribute are the attributes of a sentry_key
+	// holding the DSN and the secret key.
+	dsnAttribute    string
+	secretAttribute string
+	// releaseDeployments is whether the provider has a
+	// sentry_release_deployment resource.
+	releaseDeployments bool
+	// encodedAlertRules is whether the provider expects the conditions,
+	// filters and actions of issue alerts as JSON encoded strings.
+	encodedAlertRules bool
+}
+
+var providerBackends = map[string]providerBackend{
+	providerSourceLabd: {
+		source:             providerSourceLabd,
+		defaultVersion:     "1.0.2",
+		resourcesTemplate:  "templates/labd/resources.tmpl",
+		dsnAttribute:       "dsn_secret",
+		secretAttribute:    "secret",
+		releaseDeployments: true,
+	},
+	providerSourceJianyuan: {
+		source:             providerSourceJianyuan,
+		defaultVersion:     "0.14.5",
+		resourcesTemplate:  "templates/jianyuan/resources.tmpl",
+		dsnAttribute:       `dsn["secret"]`,
+		secretAttribute:    "secret",
+		releaseDeployments: false,
+		encodedAlertRules:  true,
+	},
+}
+
+// getProviderBackend returns the backend for the configured provider source,
+// defaulting to the labd/sentry provider.
+func getProviderBackend(source string) (providerBackend, error) {
+	if source == "" {
+		source = providerSourceLabd
+	}
+	backend, ok := providerBackends[source]
+	if !ok {
+		return providerBackend{}, fmt.Errorf("unsupported provider_source %s", source)
+	}
+	return backend, nil
+}
@@ -0,0 +1,21 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetProviderBackend(t *testing.T) {
+	backend, err := getProviderBackend("")
+	assert.NoError(t, err)
+	assert.Equal(t, providerSourceLabd, backend.source)
+
+	backend, err = getProviderBackend(providerSourceJianyuan)
+	assert.NoError(t, err)
+	assert.Equal(t, providerSourceJianyuan, backend.source)
+	assert.False(t, backend.releaseDeployments)
+
+	_, err = getProviderBackend("hashicorp/sentry")
+	assert.ErrorContains(t, err, "unsupported provider_source hashicorp/sentry")
+}
@@ -0,0 +1,303 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"gopkg.in/yaml.v3"
+)
+
+const cliUsage = `usage: mach-composer-sentry <command> [flags]
+
+commands:
+  render    render the Sentry Terraform code of a site
+  explain   show the effective configuration of the components of a site
+  validate  check the Sentry configuration of all sites and components`
+
+const (
+	formatHCL  = "hcl"
+	formatJSON = "json"
+)
+
+// machConfig is the part of a mach-composer config file read by the command
+// line tool.
+type machConfig struct {
+	Global struct {
+		Environment     string         `yaml:"environment"`
+		Sentry          map[string]any `yaml:"sentry"`
+		TerraformConfig struct {
+			Providers map[string]string `yaml:"providers"`
+		} `yaml:"terraform_config"`
+	} `yaml:"global"`
+	Sites []struct {
+		Identifier string         `yaml:"identifier"`
+		Sentry     map[string]any `yaml:"sentry"`
+		Components []struct {
+			Name   string         `yaml:"name"`
+			Sentry map[string]any `yaml:"sentry"`
+		} `yaml:"components"`
+	} `yaml:"sites"`
+	Components []struct {
+		Name         string         `yaml:"name"`
+		Version      string         `yaml:"version"`
+		Integrations []string       `yaml:"integrations"`
+		Sentry       map[string]any `yaml:"sentry"`
+	} `yaml:"components"`
+}
+
+// RunCLI runs the plugin as a command line tool, to inspect the Sentry
+// configuration of a mach-composer config file outside of mach-composer.
+func RunCLI(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(cliUsage)
+	}
+
+	switch args[0] {
+	case "render":
+		return runRender(args[1:], stdout)
+	case "explain":
+		return runExplain(args[1:], stdout)
+	case "validate":
+		return runValidate(args[1:], stdout)
+	default:
+		return fmt.Errorf("unknown command %s\n%s", args[0], cliUsage)
+	}
+}
+
+func runRender(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	configFile := flags.String("config", "main.yml", "mach-composer config file")
+	site := flags.String("site", "", "site to render")
+	format := flags.String("format", formatHCL, "output format, hcl or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *site == "" {
+		return errors.New("render requires -site to be set")
+	}
+
+	p, cfg, err := loadMachConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	components, err := cfg.siteComponents(*site)
+	if err != nil {
+		return err
+	}
+
+	var output []byte
+	switch *format {
+	case formatHCL:
+		output, err = p.renderTerraformHCL(*site, components)
+	case formatJSON:
+		output, err = p.RenderTerraformJSON(*site, components)
+	default:
+		return fmt.Errorf("unsupported format %s", *format)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(stdout, strings.TrimSpace(string(output)))
+	return err
+}
+
+func runExplain(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("explain", flag.ContinueOnError)
+	configFile := flags.String("config", "main.yml", "mach-composer config file")
+	site := flags.String("site", "", "site to explain, defaults to all sites")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	p, cfg, err := loadMachConfig(*configFile)
+	if err != nil {
+		return err
+	}
+
+	var sites []string
+	for _, s := range cfg.Sites {
+		if *site == "" || s.Identifier == *site {
+			sites = append(sites, s.Identifier)
+		}
+	}
+	if len(sites) == 0 {
+		return fmt.Errorf("site %s not found", *site)
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	for i, site := range sites {
+		components, err := cfg.siteComponents(site)
+		if err != nil {
+			return err
+		}
+		for j, component := range components {
+			if i > 0 || j > 0 {
+				if _, err := fmt.Fprintln(w); err != nil {
+					return err
+				}
+			}
+			if _, err := fmt.Fprintf(w, "site %s, component %s\n", site, component); err != nil {
+				return err
+			}
+			fields, err := p.Explain(site, component)
+			if err != nil {
+				return err
+			}
+			for _, field := range fields {
+				if _, err := fmt.Fprintf(w, "  %s\t(%s)\t%s\n", field.Name, field.Level, formatConfigValue(field.Value)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return w.Flush()
+}
+
+func runValidate(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	configFile := flags.String("config", "main.yml", "mach-composer config file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	p, _, err := loadMachConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	if err := p.Validate(); err != nil {
+		return fmt.Errorf("invalid sentry config:\n%w", err)
+	}
+
+	_, err = fmt.Fprintln(stdout, "sentry config is valid")
+	return err
+}
+
+// formatConfigValue formats a config value as JSON, or a dash when it is not
+// set.
+func formatConfigValue(value any) string {
+	if value == nil {
+		return "-"
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
+
+// loadMachConfig reads a mach-composer config file and passes the Sentry
+// configuration to a new plugin, the same way mach-composer does.
+func loadMachConfig(filename string) (*SentryPlugin, *machConfig, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cfg := &machConfig{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, nil, fmt.Errorf("invalid mach-composer config %s: %w", filename, err)
+	}
+
+	p := NewSentryPlugin()
+	if err := p.Configure(cfg.Global.Environment, cfg.Global.TerraformConfig.Providers["sentry"]); err != nil {
+		return nil, nil, err
+	}
+	if err := p.SetGlobalConfig(orEmpty(cfg.Global.Sentry)); err != nil {
+		return nil, nil, err
+	}
+	integrated := map[string]bool{}
+	for _, component := range cfg.Components {
+		if !slices.Contains(component.Integrations, "sentry") {
+			continue
+		}
+		integrated[component.Name] = true
+		if err := p.SetComponentConfig(component.Name, component.Version, orEmpty(component.Sentry)); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, site := range cfg.Sites {
+		if err := p.SetSiteConfig(site.Identifier, orEmpty(site.Sentry)); err != nil {
+			return nil, nil, err
+		}
+		for _, component := range site.Components {
+			if !integrated[component.Name] {
+				continue
+			}
+			if err := p.SetSiteComponentConfig(site.Identifier, component.Name, orEmpty(component.Sentry)); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return p, cfg, nil
+}
+
+// siteComponents returns the components of a site using the Sentry plugin.
+func (c *machConfig) siteComponents(site string) ([]string, error) {
+	integrated := map[string]bool{}
+	for _, component := range c.Components {
+		integrated[component.Name] = slices.Contains(component.Integrations, "sentry")
+	}
+
+	for _, s := range c.Sites {
+		if s.Identifier != site {
+			continue
+		}
+		var result []string
+		for _, component := range s.Components {
+			if integrated[component.Name] {
+				result = append(result, component.Name)
+			}
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("site %s not found", site)
+}
+
+// renderTerraformHCL renders the provider, the site resources and the
+// resources of the given components of a site as a single HCL document.
+func (p *SentryPlugin) renderTerraformHCL(site string, components []string) ([]byte, error) {
+	var b strings.Builder
+
+	providers, err := p.RenderTerraformProviders(site)
+	if err != nil {
+		return nil, err
+	}
+	if providers != "" {
+		fmt.Fprintf(&b, "terraform {\nrequired_providers {%s\n}\n}\n", providers)
+	}
+
+	resources, err := p.RenderTerraformResources(site)
+	if err != nil {
+		return nil, err
+	}
+	b.WriteString(resources)
+
+	for _, component := range components {
+		result, err := p.RenderTerraformComponent(site, component)
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString(result.Resources)
+	}
+
+	// The templates leave blank lines around optional properties.
+	blankLines := regexp.MustCompile(`\n\s*\n(\s*\n)+`)
+	return hclwrite.Format(blankLines.ReplaceAll([]byte(b.String()), []byte("\n\n"))), nil
+}
+
+func orEmpty(data map[string]any) map[string]any {
+	if data == nil {
+		return map[string]any{}
+	}
+	return data
+}
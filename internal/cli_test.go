@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testMachConfig = `
+global:
+  environment: test
+  sentry:
+    auth_token: foobar
+    organization: my-org
+    project: my-project
+sites:
+  - identifier: my-site
+    components:
+      - name: my-component
+        sentry:
+          rate_limit_window: 60
+      - name: other-component
+components:
+  - name: my-component
+    version: "1.0.0"
+    integrations: ["sentry"]
+  - name: other-component
+    version: "1.0.0"
+    integrations: ["aws"]
+`
+
+func writeMachConfig(t *testing.T) string {
+	filename := filepath.Join(t.TempDir(), "main.yml")
+	assert.NoError(t, os.WriteFile(filename, []byte(testMachConfig), 0o600))
+	return filename
+}
+
+func TestRunCLIRender(t *testing.T) {
+	filename := writeMachConfig(t)
+
+	var out bytes.Buffer
+	err := RunCLI([]string{"render", "-config", filename, "-site", "my-site"}, &out)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), `source  = "labd/sentry"`)
+	assert.Contains(t, out.String(), `resource "sentry_key" "my-component" {`)
+	assert.Contains(t, out.String(), "rate_limit_window = 60")
+	assert.NotContains(t, out.String(), "other-component")
+}
+
+func TestRunCLIRenderJSON(t *testing.T) {
+	filename := writeMachConfig(t)
+
+	var out bytes.Buffer
+	err := RunCLI([]string{"render", "-config", filename, "-site", "my-site", "-format", "json"}, &out)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), `"sentry_key": {`)
+	assert.Contains(t, out.String(), `"rate_limit_window": 60`)
+}
+
+func TestRunCLIErrors(t *testing.T) {
+	filename := writeMachConfig(t)
+
+	var out bytes.Buffer
+	assert.ErrorContains(t, RunCLI(nil, &out), "usage:")
+	assert.ErrorContains(t, RunCLI([]string{"unknown"}, &out), "unknown command unknown")
+	assert.EqualError(t, RunCLI([]string{"render", "-config", filename}, &out), "render requires -site to be set")
+	assert.EqualError(t, RunCLI([]string{"render", "-config", filename, "-site", "other"}, &out), "site other not found")
+	assert.EqualError(t, RunCLI([]string{"render", "-config", filename, "-site", "my-site", "-format", "xml"}, &out), "unsupported format xml")
+}
+
+func TestRunCLIExplain(t *testing.T) {
+	filename := writeMachConfig(t)
+
+	var out bytes.Buffer
+	err := RunCLI([]string{"explain", "-config", filename}, &out)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "site my-site, component my-component\n")
+	assert.Regexp(t, `rate_limit_window\s+\(component\)\s+60\n`, out.String())
+	assert.Regexp(t, `project\s+\(global\)\s+"my-project"\n`, out.String())
+	assert.Regexp(t, `platform\s+\(default\)\s+-\n`, out.String())
+	assert.NotContains(t, out.String(), "other-component")
+
+	assert.EqualError(t, RunCLI([]string{"explain", "-config", filename, "-site", "other"}, &out), "site other not found")
+}
+
+func TestRunCLIValidate(t *testing.T) {
+	filename := writeMachConfig(t)
+
+	var out bytes.Buffer
+	err := RunCLI([]string{"validate", "-config", filename}, &out)
+	assert.NoError(t, err)
+	assert.Equal(t, "sentry config is valid\n", out.String())
+}
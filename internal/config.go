@@ -1,21 +1,207 @@
 package internal
 
+import (
+	"fmt"
+)
+
+const (
+	// disabledVariablesEmpty passes the variables with empty values to
+	// disabled components, so components requiring them keep working.
+	disabledVariablesEmpty = "empty"
+	// disabledVariablesNone passes no variables to disabled components.
+	disabledVariablesNone = "none"
+)
+
+const (
+	// modeManaged creates the Sentry keys (and optionally projects) through the
+	// Terraform provider.
+	modeManaged = "managed"
+	// modeUnmanaged only passes a preconfigured DSN to the components.
+	modeUnmanaged = "unmanaged"
+	// modeLookup reads existing Sentry keys through a data source, which only
+	// requires a token with read access.
+	modeLookup = "lookup"
+)
+
 // BaseConfig is the base sentry config.
 type BaseConfig struct {
-	DSN              string `mapstructure:"dsn"`
-	RateLimitWindow  *int   `mapstructure:"rate_limit_window"`
-	RateLimitCount   *int   `mapstructure:"rate_limit_count"`
-	Project          string `mapstructure:"project"`
-	TrackDeployments *bool  `mapstructure:"track_deployments"`
-	ExposeKey        *bool  `mapstructure:"expose_key"`
+	Mode                string          `mapstructure:"mode"`
+	Enabled             *bool           `mapstructure:"enabled"`
+	DisabledVariables   string          `mapstructure:"disabled_variables"`
+	DSN                 string          `mapstructure:"dsn"`
+	RateLimitWindow     *int            `mapstructure:"rate_limit_window"`
+	RateLimitCount      *int            `mapstructure:"rate_limit_count"`
+	Project             string          `mapstructure:"project"`
+	TrackDeployments    *bool           `mapstructure:"track_deployments"`
+	ExposeKey           *bool           `mapstructure:"expose_key"`
+	ExposeRelease       *bool           `mapstructure:"expose_release"`
+	ReleaseNameTemplate string          `mapstructure:"release_name_template"`
+	CreateProject       *bool           `mapstructure:"create_project"`
+	ProjectOwnerSite    string          `mapstructure:"project_owner_site"`
+	ProjectName         string          `mapstructure:"project_name"`
+	Platform            string          `mapstructure:"platform"`
+	Team                string          `mapstructure:"team"`
+	KeyName             string          `mapstructure:"key_name"`
+	KeyNameTemplate     string          `mapstructure:"key_name_template"`
+	KeyScope            string          `mapstructure:"key_scope"`
+	KeyOwnerSite        string          `mapstructure:"key_owner_site"`
+	KeyRotation         *KeyRotation    `mapstructure:"key_rotation"`
+	Alerts              []IssueAlert    `mapstructure:"alerts"`
+	MetricAlerts        []MetricAlert   `mapstructure:"metric_alerts"`
+	InboundFilters      *InboundFilters `mapstructure:"inbound_filters"`
+	SpikeProtection     *bool           `mapstructure:"spike_protection"`
+}
+
+// KeyRotation configures the rotation of the Sentry key of a component. Bumping
+// the generation creates a new key which is passed to the component, while
+// the keys of the last KeepGenerations generations are kept. Older keys are
+// removed, so a key stays active for a number of rotations rather than a
+// number of deployments.
+type KeyRotation struct {
+	Generation      int  `mapstructure:"generation"`
+	KeepGenerations *int `mapstructure:"keep_generations"`
+}
+
+// InboundFilters configures the inbound data filters of the project of a
+// component. Filters which are not set are left untouched.
+type InboundFilters struct {
+	BrowserExtensions *bool    `mapstructure:"browser_extensions"`
+	Localhost         *bool    `mapstructure:"localhost"`
+	WebCrawlers       *bool    `mapstructure:"web_crawlers"`
+	LegacyBrowsers    []string `mapstructure:"legacy_browsers"`
+	ErrorMessages     []string `mapstructure:"error_messages"`
+	Releases          []string `mapstructure:"releases"`
+}
+
+// extend returns the filters with the unset filters taken from the parent
+// level.
+func (f *InboundFilters) extend(parent *InboundFilters) *InboundFilters {
+	if f == nil {
+		return parent
+	}
+	if parent == nil {
+		return f
+	}
+
+	cfg := *parent
+	if f.BrowserExtensions != nil {
+		cfg.BrowserExtensions = f.BrowserExtensions
+	}
+	if f.Localhost != nil {
+		cfg.Localhost = f.Localhost
+	}
+	if f.WebCrawlers != nil {
+		cfg.WebCrawlers = f.WebCrawlers
+	}
+	if f.LegacyBrowsers != nil {
+		cfg.LegacyBrowsers = f.LegacyBrowsers
+	}
+	if f.ErrorMessages != nil {
+		cfg.ErrorMessages = f.ErrorMessages
+	}
+	if f.Releases != nil {
+		cfg.Releases = f.Releases
+	}
+	return &cfg
+}
+
+// IssueAlert is an issue alert rule created for the project of a component.
+// Conditions, filters and actions are passed to the provider as-is.
+type IssueAlert struct {
+	Name        string           `mapstructure:"name"`
+	ActionMatch string           `mapstructure:"action_match"`
+	FilterMatch string           `mapstructure:"filter_match"`
+	Frequency   int              `mapstructure:"frequency"`
+	Environment string           `mapstructure:"environment"`
+	Conditions  []map[string]any `mapstructure:"conditions"`
+	Filters     []map[string]any `mapstructure:"filters"`
+	Actions     []map[string]any `mapstructure:"actions"`
+}
+
+// MetricAlert is a metric alert created for the project of a component, for
+// example on the error rate or the crash free session rate.
+type MetricAlert struct {
+	Name             string               `mapstructure:"name"`
+	Dataset          string               `mapstructure:"dataset"`
+	Query            string               `mapstructure:"query"`
+	Aggregate        string               `mapstructure:"aggregate"`
+	TimeWindow       int                  `mapstructure:"time_window"`
+	ThresholdType    int                  `mapstructure:"threshold_type"`
+	ResolveThreshold *float64             `mapstructure:"resolve_threshold"`
+	Environment      string               `mapstructure:"environment"`
+	Triggers         []MetricAlertTrigger `mapstructure:"triggers"`
+}
+
+// MetricAlertTrigger is a threshold of a metric alert with the actions to
+// execute when it is crossed.
+type MetricAlertTrigger struct {
+	Label            string              `mapstructure:"label"`
+	AlertThreshold   float64             `mapstructure:"alert_threshold"`
+	ResolveThreshold *float64            `mapstructure:"resolve_threshold"`
+	ThresholdType    *int                `mapstructure:"threshold_type"`
+	Actions          []MetricAlertAction `mapstructure:"actions"`
+}
+
+// MetricAlertAction is a notification sent when a metric alert trigger fires.
+type MetricAlertAction struct {
+	Type             string `mapstructure:"type"`
+	TargetType       string `mapstructure:"target_type"`
+	TargetIdentifier string `mapstructure:"target_identifier"`
+	IntegrationID    *int   `mapstructure:"integration_id"`
+}
+
+// enabled returns whether the plugin renders anything for the components.
+func (c *BaseConfig) enabled() bool {
+	return c.Enabled == nil || *c.Enabled
+}
+
+// mode returns the effective plugin mode. When no mode is configured the
+// plugin runs in managed mode if an auth token is set.
+func (c *BaseConfig) mode(g GlobalConfig) string {
+	if c.Mode != "" {
+		return c.Mode
+	}
+	if g.AuthToken != "" {
+		return modeManaged
+	}
+	return modeUnmanaged
 }
 
 // GlobalConfig global Sentry configuration.
 type GlobalConfig struct {
-	BaseConfig   `mapstructure:",squash"`
-	AuthToken    string `mapstructure:"auth_token"`
-	BaseURL      string `mapstructure:"base_url"`
-	Organization string `mapstructure:"organization"`
+	BaseConfig     `mapstructure:",squash"`
+	AuthToken      string `mapstructure:"auth_token"`
+	BaseURL        string `mapstructure:"base_url"`
+	Organization   string `mapstructure:"organization"`
+	ProviderSource string `mapstructure:"provider_source"`
+	Teams          []Team `mapstructure:"teams"`
+
+	// EnvironmentMapping maps mach-composer environments onto Sentry
+	// environments.
+	EnvironmentMapping map[string]string `mapstructure:"environment_mapping"`
+
+	// Environments holds settings per mach-composer environment, overriding
+	// the global settings.
+	Environments map[string]BaseConfig `mapstructure:"environments"`
+}
+
+// Team is a Sentry team managed by the plugin. When a site is set, the team is
+// only created in that site and looked up by all other sites, which is
+// required when multiple sites share the same organization.
+type Team struct {
+	Slug string `mapstructure:"slug"`
+	Name string `mapstructure:"name"`
+	Site string `mapstructure:"site"`
+}
+
+// getTeam returns the managed team with the given slug.
+func (c *GlobalConfig) getTeam(slug string) (Team, bool) {
+	for _, team := range c.Teams {
+		if team.Slug == slug {
+			return team, true
+		}
+	}
+	return Team{}, false
 }
 
 func newGlobalConfig() GlobalConfig {
@@ -23,14 +209,27 @@ func newGlobalConfig() GlobalConfig {
 		BaseConfig: BaseConfig{
 			TrackDeployments: boolPtr(true),
 			ExposeKey:        boolPtr(false),
+			ExposeRelease:    boolPtr(false),
+			CreateProject:    boolPtr(false),
 		},
 	}
 }
 
 // SiteConfig is for site specific sentry DSN settings
 type SiteConfig struct {
-	BaseConfig `mapstructure:",squash"`
-	Components map[string]SiteComponentConfig `mapstructure:"-"`
+	BaseConfig   `mapstructure:",squash"`
+	AuthToken    string                         `mapstructure:"auth_token"`
+	BaseURL      string                         `mapstructure:"base_url"`
+	Organization string                         `mapstructure:"organization"`
+	Components   map[string]SiteComponentConfig `mapstructure:"-"`
+
+	// EnvironmentMapping maps mach-composer environments onto Sentry
+	// environments for the site, overriding the global mapping.
+	EnvironmentMapping map[string]string `mapstructure:"environment_mapping"`
+
+	// Environments holds settings per mach-composer environment, overriding
+	// the settings of the site.
+	Environments map[string]BaseConfig `mapstructure:"environments"`
 }
 
 func newSiteConfig() SiteConfig {
@@ -42,68 +241,142 @@ func newSiteConfig() SiteConfig {
 // SiteComponentConfig is for component specific sentry DSN settings
 type SiteComponentConfig struct {
 	BaseConfig `mapstructure:",squash"`
+
+	// KeyID is the ID of an existing key. In managed mode the key is imported
+	// into the Terraform state instead of creating a new key, in lookup mode
+	// the key is read by its ID.
+	KeyID string `mapstructure:"key_id"`
 }
 
 var defaultSiteComponentConfig = SiteComponentConfig{}
 
 // ComponentConfig is for general component information
 type ComponentConfig struct {
-	Version string `mapstructure:"-"`
+	Version       string   `mapstructure:"-"`
+	PreviousNames []string `mapstructure:"previous_names"`
 }
 
-func (c *SiteConfig) extendGlobalConfig(g GlobalConfig) SiteConfig {
-	cfg := SiteConfig{
-		BaseConfig: g.BaseConfig,
-		Components: c.Components,
-	}
-	if c.DSN != "" {
-		cfg.DSN = c.DSN
-	}
-	if c.RateLimitWindow != nil {
-		cfg.RateLimitWindow = c.RateLimitWindow
-	}
-	if c.RateLimitCount != nil {
-		cfg.RateLimitCount = c.RateLimitCount
-	}
-	if c.Project != "" {
-		cfg.Project = c.Project
+// providerConfig returns the global config with the provider settings
+// overridden by the site.
+func (c *SiteConfig) providerConfig(g GlobalConfig) GlobalConfig {
+	cfg := g
+	if c.AuthToken != "" {
+		cfg.AuthToken = c.AuthToken
 	}
-	if c.TrackDeployments != nil {
-		cfg.TrackDeployments = c.TrackDeployments
+	if c.BaseURL != "" {
+		cfg.BaseURL = c.BaseURL
 	}
-	if c.ExposeKey != nil {
-		cfg.ExposeKey = c.ExposeKey
+	if c.Organization != "" {
+		cfg.Organization = c.Organization
 	}
 	return cfg
 }
 
-func (c *SiteComponentConfig) extendSiteConfig(s SiteConfig) SiteComponentConfig {
-	cfg := SiteComponentConfig{
-		BaseConfig: s.BaseConfig,
+// sentryEnvironment returns the Sentry environment of the site for the given
+// mach-composer environment. The environment mapping of the site takes
+// precedence over the global environment mapping.
+func (c *SiteConfig) sentryEnvironment(g GlobalConfig, environment string) string {
+	if mapped, ok := c.EnvironmentMapping[environment]; ok {
+		return mapped
 	}
-
-	if c.DSN != "" {
-		cfg.DSN = c.DSN
+	if mapped, ok := g.EnvironmentMapping[environment]; ok {
+		return mapped
 	}
-	if c.RateLimitWindow != nil {
-		cfg.RateLimitWindow = c.RateLimitWindow
+	return environment
+}
+
+// providerAlias returns the alias of the Sentry provider used by the site. Sites
+// overriding the provider settings get their own aliased provider, all other
+// sites use the default provider.
+func (c *SiteConfig) providerAlias(site string) string {
+	if c.AuthToken == "" && c.BaseURL == "" && c.Organization == "" {
+		return ""
 	}
-	if c.RateLimitCount != nil {
-		cfg.RateLimitCount = c.RateLimitCount
+	return identifier(site)
+}
+
+func (c *SiteComponentConfig) extendSiteConfig(s SiteConfig) (SiteComponentConfig, error) {
+	cfg, err := mergeConfig(s.BaseConfig, c.BaseConfig)
+	if err != nil {
+		return SiteComponentConfig{}, err
 	}
-	if c.Project != "" {
-		cfg.Project = c.Project
+	return SiteComponentConfig{
+		BaseConfig: cfg,
+		KeyID:      c.KeyID,
+	}, nil
+}
+
+// createProject returns whether the plugin creates the project of the
+// component.
+func (c *SiteComponentConfig) createProject() bool {
+	return c.CreateProject != nil && *c.CreateProject
+}
+
+// hasProjectSettings returns whether the component manages settings of its
+// project, which can only be managed by a single site.
+func (c *SiteComponentConfig) hasProjectSettings() bool {
+	return c.createProject() || c.InboundFilters != nil || c.SpikeProtection != nil || len(c.Alerts) > 0 || len(c.MetricAlerts) > 0
+}
+
+// projectSlug returns the slug of the project of the component. Projects
+// created by the plugin default to the component name.
+func (c *SiteComponentConfig) projectSlug(component string) string {
+	if c.Project == "" && c.createProject() {
+		return component
 	}
-	if c.TrackDeployments != nil {
-		cfg.TrackDeployments = c.TrackDeployments
+	return c.Project
+}
+
+// validate checks the merged configuration of a component for settings which
+// are required by the mode or by other settings, and returns all problems.
+func (c *SiteComponentConfig) validate(g GlobalConfig) []error {
+	var errs []error
+
+	mode := c.mode(g)
+	switch mode {
+	case modeManaged:
+		if g.Organization == "" {
+			errs = append(errs, fmt.Errorf("mode %s requires organization to be set", mode))
+		}
+		if c.Project == "" && !c.createProject() {
+			errs = append(errs, fmt.Errorf("mode %s requires project to be set unless create_project is enabled", mode))
+		}
+		if c.createProject() && c.Team == "" {
+			errs = append(errs, fmt.Errorf("create_project requires a team to be set"))
+		}
+		if c.KeyScope != "" && c.KeyScope != keyScopeSite && c.KeyOwnerSite == "" {
+			errs = append(errs, fmt.Errorf("key_scope %s requires key_owner_site to be set", c.KeyScope))
+		}
+	case modeLookup:
+		if g.Organization == "" {
+			errs = append(errs, fmt.Errorf("mode %s requires organization to be set", mode))
+		}
+		if c.Project == "" {
+			errs = append(errs, fmt.Errorf("mode %s requires project to be set", mode))
+		}
+		switch {
+		case c.KeyName == "" && c.KeyID == "":
+			errs = append(errs, fmt.Errorf("mode %s requires key_name or key_id to be set", mode))
+		case c.KeyName != "" && c.KeyID != "":
+			errs = append(errs, fmt.Errorf("mode %s requires only one of key_name and key_id to be set", mode))
+		}
+	case modeUnmanaged:
+		// Without a mode, auth_token and any other Sentry settings the plugin
+		// is not set up at all, which only logs a warning. Once Sentry is
+		// configured an inferred unmanaged mode would silently pass an empty
+		// DSN, so it requires a dsn as well.
+		if c.DSN == "" && (c.Mode == modeUnmanaged || g.Organization != "" || c.Project != "") {
+			errs = append(errs, fmt.Errorf("mode %s requires dsn to be set", mode))
+		}
 	}
-	if c.ExposeKey != nil {
-		cfg.ExposeKey = c.ExposeKey
+
+	if c.RateLimitCount != nil && c.RateLimitWindow == nil {
+		errs = append(errs, fmt.Errorf("rate_limit_count requires rate_limit_window to be set"))
 	}
-	return cfg
+	return errs
 }
 
-func (c *SiteConfig) getSiteComponentConfig(name string) SiteComponentConfig {
+func (c *SiteConfig) getSiteComponentConfig(name string) (SiteComponentConfig, error) {
 	compConfig, ok := c.Components[name]
 	if !ok {
 		compConfig = defaultSiteComponentConfig
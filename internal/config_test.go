@@ -5,36 +5,170 @@ import (
 	"testing"
 )
 
-func TestExtendGlobalConfig(t *testing.T) {
-	globalConfig := GlobalConfig{
+func TestMergeConfig(t *testing.T) {
+	globalConfig := BaseConfig{
+		TrackDeployments: boolPtr(true),
+	}
+
+	siteConfig := BaseConfig{
+		TrackDeployments: boolPtr(false),
+	}
+
+	extendedCfg, err := mergeConfig(globalConfig, siteConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, false, *extendedCfg.TrackDeployments)
+}
+
+func TestMergeConfigLayers(t *testing.T) {
+	global := BaseConfig{
+		Project:         "global-project",
+		RateLimitWindow: intPtr(60),
+		RateLimitCount:  intPtr(100),
+		KeyRotation:     &KeyRotation{Generation: 2, KeepGenerations: intPtr(1)},
+		Alerts:          []IssueAlert{{Name: "global"}},
+		MetricAlerts:    []MetricAlert{{Name: "global"}},
+	}
+	site := BaseConfig{
+		Project:        "site-project",
+		RateLimitCount: intPtr(0),
+		KeyRotation:    &KeyRotation{Generation: 3},
+		Alerts:         []IssueAlert{},
+	}
+	component := BaseConfig{
+		Platform:     "go",
+		MetricAlerts: []MetricAlert{{Name: "component"}},
+	}
+
+	cfg, err := mergeConfig(global, site, component)
+	assert.NoError(t, err)
+	assert.Equal(t, BaseConfig{
+		Project:         "site-project",
+		Platform:        "go",
+		RateLimitWindow: intPtr(60),
+		RateLimitCount:  intPtr(0),
+		KeyRotation:     &KeyRotation{Generation: 3},
+		Alerts:          []IssueAlert{},
+		MetricAlerts:    []MetricAlert{{Name: "component"}},
+	}, cfg)
+
+	// The layers are not modified by the merge.
+	assert.Equal(t, 2, global.KeyRotation.Generation)
+	assert.Equal(t, 100, *global.RateLimitCount)
+}
+
+func TestExtendSiteConfig(t *testing.T) {
+	siteCfg := SiteConfig{
 		BaseConfig: BaseConfig{
 			TrackDeployments: boolPtr(true),
 		},
 	}
 
-	siteConfig := SiteConfig{
+	siteComponentConfig := SiteComponentConfig{
 		BaseConfig: BaseConfig{
 			TrackDeployments: boolPtr(false),
 		},
 	}
 
-	extendedCfg := siteConfig.extendGlobalConfig(globalConfig)
+	extendedCfg, err := siteComponentConfig.extendSiteConfig(siteCfg)
+	assert.NoError(t, err)
 	assert.Equal(t, false, *extendedCfg.TrackDeployments)
 }
 
-func TestExtendSiteConfig(t *testing.T) {
+func TestExtendSiteConfigCreateProject(t *testing.T) {
 	siteCfg := SiteConfig{
 		BaseConfig: BaseConfig{
-			TrackDeployments: boolPtr(true),
+			CreateProject: boolPtr(true),
+			Team:          "site-team",
+			Platform:      "python",
 		},
 	}
 
 	siteComponentConfig := SiteComponentConfig{
 		BaseConfig: BaseConfig{
-			TrackDeployments: boolPtr(false),
+			Team: "component-team",
 		},
 	}
 
-	extendedCfg := siteComponentConfig.extendSiteConfig(siteCfg)
-	assert.Equal(t, false, *extendedCfg.TrackDeployments)
+	extendedCfg, err := siteComponentConfig.extendSiteConfig(siteCfg)
+	assert.NoError(t, err)
+	assert.Equal(t, true, *extendedCfg.CreateProject)
+	assert.Equal(t, "component-team", extendedCfg.Team)
+	assert.Equal(t, "python", extendedCfg.Platform)
+}
+
+func TestExtendSiteConfigInboundFilters(t *testing.T) {
+	siteCfg := SiteConfig{
+		BaseConfig: BaseConfig{
+			InboundFilters: &InboundFilters{
+				BrowserExtensions: boolPtr(true),
+				LegacyBrowsers:    []string{"ie_pre_9"},
+			},
+		},
+	}
+
+	siteComponentConfig := SiteComponentConfig{
+		BaseConfig: BaseConfig{
+			InboundFilters: &InboundFilters{
+				Localhost:      boolPtr(true),
+				LegacyBrowsers: []string{},
+			},
+		},
+	}
+
+	extendedCfg, err := siteComponentConfig.extendSiteConfig(siteCfg)
+	assert.NoError(t, err)
+	assert.Equal(t, &InboundFilters{
+		BrowserExtensions: boolPtr(true),
+		Localhost:         boolPtr(true),
+		LegacyBrowsers:    []string{},
+	}, extendedCfg.InboundFilters)
+	assert.Equal(t, []string{"ie_pre_9"}, siteCfg.InboundFilters.LegacyBrowsers)
+}
+
+func TestSiteComponentConfigValidate(t *testing.T) {
+	managed := GlobalConfig{AuthToken: "foobar"}
+
+	cfg := SiteComponentConfig{}
+	cfg.RateLimitCount = intPtr(10)
+	errs := cfg.validate(managed)
+	assert.Len(t, errs, 3)
+	assert.EqualError(t, errs[0], "mode managed requires organization to be set")
+	assert.EqualError(t, errs[1], "mode managed requires project to be set unless create_project is enabled")
+	assert.EqualError(t, errs[2], "rate_limit_count requires rate_limit_window to be set")
+
+	cfg = SiteComponentConfig{}
+	cfg.Mode = modeUnmanaged
+	errs = cfg.validate(GlobalConfig{})
+	assert.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], "mode unmanaged requires dsn to be set")
+
+	cfg = SiteComponentConfig{}
+	cfg.Mode = modeLookup
+	cfg.KeyName = "production"
+	errs = cfg.validate(GlobalConfig{Organization: "my-org"})
+	assert.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], "mode lookup requires project to be set")
+
+	cfg = SiteComponentConfig{KeyID: "abcdef"}
+	cfg.Mode = modeLookup
+	cfg.Project = "my-project"
+	cfg.KeyName = "production"
+	errs = cfg.validate(GlobalConfig{Organization: "my-org"})
+	assert.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], "mode lookup requires only one of key_name and key_id to be set")
+
+	// Without auth token, mode or other settings the plugin is not set up,
+	// which is valid.
+	cfg = SiteComponentConfig{}
+	assert.Empty(t, cfg.validate(GlobalConfig{}))
+
+	// Once Sentry is configured the inferred unmanaged mode requires a DSN.
+	cfg = SiteComponentConfig{}
+	cfg.Project = "my-project"
+	errs = cfg.validate(GlobalConfig{})
+	assert.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], "mode unmanaged requires dsn to be set")
+
+	cfg.DSN = "https://sentry.io/123"
+	assert.Empty(t, cfg.validate(GlobalConfig{}))
 }
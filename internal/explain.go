@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"reflect"
+)
+
+// Levels of the configuration a setting can be resolved from.
+const (
+	levelDefault           = "default"
+	levelGlobal            = "global"
+	levelGlobalEnvironment = "global environment"
+	levelSite              = "site"
+	levelSiteEnvironment   = "site environment"
+	levelComponent         = "component"
+)
+
+// ConfigField is a setting of the effective configuration of a component
+// together with the level it was resolved from.
+type ConfigField struct {
+	Name  string
+	Value any
+	Level string
+}
+
+// configLayer is one level of the configuration inheritance chain.
+type configLayer struct {
+	level  string
+	config BaseConfig
+}
+
+// configLayers returns the levels of the configuration of a component in a
+// site, ordered from the lowest to the highest precedence. The settings of the
+// environment the plugin is configured for override the global and site
+// settings. Without a component the levels of the site are returned.
+func (p *SentryPlugin) configLayers(site, component string) []configLayer {
+	siteCfg := p.siteConfigs[site]
+	layers := []configLayer{
+		{level: levelDefault, config: newGlobalConfig().BaseConfig},
+		{level: levelGlobal, config: p.globalLayer},
+	}
+	if overlay, ok := p.globalConfig.Environments[p.environment]; ok {
+		layers = append(layers, configLayer{level: levelGlobalEnvironment, config: overlay})
+	}
+	layers = append(layers, configLayer{level: levelSite, config: siteCfg.BaseConfig})
+	if overlay, ok := siteCfg.Environments[p.environment]; ok {
+		layers = append(layers, configLayer{level: levelSiteEnvironment, config: overlay})
+	}
+	if component != "" {
+		layers = append(layers, configLayer{level: levelComponent, config: siteCfg.Components[component].BaseConfig})
+	}
+	return layers
+}
+
+// mergeLayers merges the levels of a configuration.
+func mergeLayers(layers []configLayer) (BaseConfig, error) {
+	configs := make([]BaseConfig, len(layers))
+	for i, layer := range layers {
+		configs[i] = layer.config
+	}
+	return mergeConfig(configs...)
+}
+
+// Explain returns the effective configuration of a component in a site, with
+// the level each setting was resolved from. A setting is resolved from the
+// highest level setting it, settings which are not set on any level are
+// reported as default. Inbound filters are merged per filter and reported at
+// the highest level setting any of them.
+func (p *SentryPlugin) Explain(site, component string) ([]ConfigField, error) {
+	layers := p.configLayers(site, component)
+	effective, err := mergeLayers(layers)
+	if err != nil {
+		return nil, err
+	}
+
+	value := reflect.ValueOf(effective)
+	fields := make([]ConfigField, 0, value.NumField()+1)
+	for i := 0; i < value.NumField(); i++ {
+		level := levelDefault
+		for _, layer := range layers {
+			if !reflect.ValueOf(layer.config).Field(i).IsZero() {
+				level = layer.level
+			}
+		}
+		fields = append(fields, ConfigField{
+			Name:  value.Type().Field(i).Tag.Get("mapstructure"),
+			Value: fieldValue(value.Field(i)),
+			Level: level,
+		})
+	}
+
+	keyID := ConfigField{Name: "key_id", Level: levelDefault}
+	if id := p.siteConfigs[site].Components[component].KeyID; id != "" {
+		keyID.Value = id
+		keyID.Level = levelComponent
+	}
+	return append(fields, keyID), nil
+}
+
+// fieldValue returns the value of a config field as plain data, with structs
+// converted to maps keyed by their config names. Unset fields are nil.
+func fieldValue(v reflect.Value) any {
+	if v.IsZero() {
+		return nil
+	}
+	return plainValue(v)
+}
+
+func plainValue(v reflect.Value) any {
+	//nolint:exhaustive // all other kinds are returned as they are
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return plainValue(v.Elem())
+	case reflect.Struct:
+		result := map[string]any{}
+		for i := 0; i < v.NumField(); i++ {
+			if value := fieldValue(v.Field(i)); value != nil {
+				result[v.Type().Field(i).Tag.Get("mapstructure")] = value
+			}
+		}
+		return result
+	case reflect.Slice:
+		result := make([]any, v.Len())
+		for i := range result {
+			result[i] = plainValue(v.Index(i))
+		}
+		return result
+	default:
+		return v.Interface()
+	}
+}
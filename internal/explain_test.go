@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func explainedField(fields []ConfigField, name string) ConfigField {
+	for _, field := range fields {
+		if field.Name == name {
+			return field
+		}
+	}
+	return ConfigField{}
+}
+
+func TestExplain(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":        "foobar",
+		"organization":      "my-org",
+		"project":           "global-project",
+		"rate_limit_window": 60,
+		"rate_limit_count":  100,
+		"key_rotation": map[string]any{
+			"generation": 2,
+		},
+	})
+	p.SetSiteConfig("my-site", map[string]any{
+		"project": "site-project",
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+		"rate_limit_count": 10,
+		"key_id":           "abcdef",
+	})
+
+	fields, err := p.Explain("my-site", "my-component")
+	assert.NoError(t, err)
+
+	assert.Equal(t, ConfigField{Name: "project", Value: "site-project", Level: levelSite}, explainedField(fields, "project"))
+	assert.Equal(t, ConfigField{Name: "rate_limit_window", Value: 60, Level: levelGlobal}, explainedField(fields, "rate_limit_window"))
+	assert.Equal(t, ConfigField{Name: "rate_limit_count", Value: 10, Level: levelComponent}, explainedField(fields, "rate_limit_count"))
+	assert.Equal(t, ConfigField{Name: "track_deployments", Value: true, Level: levelDefault}, explainedField(fields, "track_deployments"))
+	assert.Equal(t, ConfigField{Name: "expose_key", Value: false, Level: levelDefault}, explainedField(fields, "expose_key"))
+	assert.Equal(t, ConfigField{Name: "platform", Value: nil, Level: levelDefault}, explainedField(fields, "platform"))
+	assert.Equal(t, ConfigField{Name: "key_id", Value: "abcdef", Level: levelComponent}, explainedField(fields, "key_id"))
+	assert.Equal(t, ConfigField{
+		Name:  "key_rotation",
+		Value: map[string]any{"generation": 2},
+		Level: levelGlobal,
+	}, explainedField(fields, "key_rotation"))
+}
+
+func TestExplainGlobalOverridesDefault(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"track_deployments": false,
+	})
+
+	fields, err := p.Explain("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Equal(t, ConfigField{Name: "track_deployments", Value: false, Level: levelGlobal}, explainedField(fields, "track_deployments"))
+}
+
+func TestExplainEnvironmentOverlays(t *testing.T) {
+	p := NewSentryPlugin()
+	p.Configure("production", "")
+
+	p.SetGlobalConfig(map[string]any{
+		"project": "global-project",
+		"environments": map[string]any{
+			"production": map[string]any{
+				"rate_limit_count": 1000,
+			},
+		},
+	})
+	p.SetSiteConfig("my-site", map[string]any{
+		"environments": map[string]any{
+			"production": map[string]any{
+				"project": "production-project",
+			},
+		},
+	})
+
+	fields, err := p.Explain("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Equal(t, ConfigField{Name: "project", Value: "production-project", Level: levelSiteEnvironment}, explainedField(fields, "project"))
+	assert.Equal(t, ConfigField{Name: "rate_limit_count", Value: 1000, Level: levelGlobalEnvironment}, explainedField(fields, "rate_limit_count"))
+}
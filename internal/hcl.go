@@ -0,0 +1,41 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// validateHCL parses a rendered Terraform fragment so template bugs or
+// unexpected config values are reported by the plugin instead of surfacing as
+// a parse error in the generated site directory.
+func validateHCL(content string, filename string) error {
+	_, diags := hclsyntax.ParseConfig([]byte(content), filename, hcl.InitialPos)
+	idx := slices.IndexFunc(diags, func(d *hcl.Diagnostic) bool { return d.Severity == hcl.DiagError })
+	if idx < 0 {
+		return nil
+	}
+
+	diag := diags[idx]
+	msg := diag.Summary
+	if diag.Detail != "" {
+		msg = fmt.Sprintf("%s: %s", msg, diag.Detail)
+	}
+	if diag.Subject != nil {
+		msg = fmt.Sprintf("%s\n%d: %s", msg, diag.Subject.Start.Line, snippet(content, diag.Subject.Start.Line))
+	}
+	return errors.New(msg)
+}
+
+// snippet returns the given line (1-based) of the content.
+func snippet(content string, line int) string {
+	lines := strings.Split(content, "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	return strings.TrimSpace(lines[line-1])
+}
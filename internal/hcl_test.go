@@ -0,0 +1,26 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateHCL(t *testing.T) {
+	err := validateHCL(`
+resource "sentry_key" "my-component" {
+  name = "my-key"
+}
+`, "test.tf")
+	assert.NoError(t, err)
+}
+
+func TestValidateHCLInvalid(t *testing.T) {
+	err := validateHCL(`
+resource "sentry_key" "my-component" {
+  name = "my-key
+}
+`, "test.tf")
+	assert.ErrorContains(t, err, "Invalid multi-line string")
+	assert.ErrorContains(t, err, `3: name = "my-key`)
+}
@@ -0,0 +1,27 @@
+package internal
+
+import (
+	"strings"
+)
+
+// identifier converts a name into a valid Terraform identifier to be used in
+// resource labels. Letters, digits, underscores and dashes are kept, all other
+// characters are replaced with an underscore. Identifiers can't start with a
+// digit or a dash, so these are prefixed with an underscore.
+func identifier(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+
+	result := b.String()
+	if result == "" || (result[0] >= '0' && result[0] <= '9') || result[0] == '-' {
+		result = "_" + result
+	}
+	return result
+}
@@ -0,0 +1,28 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdentifier(t *testing.T) {
+	tests := map[string]string{
+		"my-component":   "my-component",
+		"my_component":   "my_component",
+		"MyComponent":    "MyComponent",
+		"my.component":   "my_component",
+		"team/component": "team_component",
+		"1st-component":  "_1st-component",
+		"-component":     "_-component",
+		"my component":   "my_component",
+		"componént":      "compon_nt",
+		"":               "_",
+	}
+
+	for name, expected := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, expected, identifier(name))
+		})
+	}
+}
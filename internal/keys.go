@@ -0,0 +1,185 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/mach-composer/mach-composer-plugin-helpers/helpers"
+)
+
+const (
+	// keyScopeSite creates a key per component for every site.
+	keyScopeSite = "site"
+	// keyScopeComponent shares the key of a component between all sites.
+	keyScopeComponent = "component"
+	// keyScopeEnvironment shares a single key per project between all
+	// components and sites.
+	keyScopeEnvironment = "environment"
+)
+
+var defaultKeyNameTemplates = map[string]string{
+	keyScopeSite:        "{{ .Environment }}-{{ .Site }}-{{ .Component }}",
+	keyScopeComponent:   "{{ .Environment }}-{{ .Component }}",
+	keyScopeEnvironment: "{{ .Environment }}",
+}
+
+// sentryKey describes the Sentry key used by a component.
+type sentryKey struct {
+	// Label is the label of the sentry_key resource or data source.
+	Label string
+	// Name is the name of the key in Sentry.
+	Name string
+	// Managed is whether the key is created by the site. Keys shared with
+	// other sites are only created by the owning site and read by the others.
+	Managed bool
+	// Render is false when the key is shared with another component of the
+	// site which already renders it.
+	Render bool
+	// Generation is the rotation generation of the key.
+	Generation int
+	// Previous holds the keys of previous generations which are kept after a
+	// key rotation.
+	Previous []sentryKey
+}
+
+// Generations returns the previous keys which are still kept followed by the
+// current key.
+func (k sentryKey) Generations() []sentryKey {
+	return append(slices.Clone(k.Previous), k)
+}
+
+// generation returns the key for the given rotation generation. Generation 0
+// is the original key so enabling rotation does not replace existing keys.
+func (k sentryKey) generation(generation int) sentryKey {
+	if generation == 0 {
+		return k
+	}
+	k.Label = fmt.Sprintf("%s%s", k.Label, generationSuffix(generation))
+	k.Name = fmt.Sprintf("%s-v%d", k.Name, generation)
+	k.Generation = generation
+	return k
+}
+
+// generationSuffix returns the suffix of the resource label of a key
+// generation.
+func generationSuffix(generation int) string {
+	if generation == 0 {
+		return ""
+	}
+	return fmt.Sprintf("_v%d", generation)
+}
+
+// original returns the key of generation 0, the key existing before rotation
+// was enabled, as long as it is still kept.
+func (k sentryKey) original() (sentryKey, bool) {
+	for _, generation := range k.Generations() {
+		if generation.Generation == 0 {
+			return generation, true
+		}
+	}
+	return sentryKey{}, false
+}
+
+// rotate applies the key rotation to the key.
+func (k sentryKey) rotate(rotation *KeyRotation) sentryKey {
+	if rotation == nil || rotation.Generation <= 0 {
+		return k
+	}
+
+	keep := 1
+	if rotation.KeepGenerations != nil {
+		keep = *rotation.KeepGenerations
+	}
+
+	key := k.generation(rotation.Generation)
+	if !key.Managed {
+		return key
+	}
+	for generation := max(0, rotation.Generation-keep); generation < rotation.Generation; generation++ {
+		key.Previous = append(key.Previous, k.generation(generation))
+	}
+	return key
+}
+
+// address returns the Terraform address of the key.
+func (k sentryKey) address() string {
+	if k.Managed {
+		return fmt.Sprintf("sentry_key.%s", k.Label)
+	}
+	return fmt.Sprintf("data.sentry_key.%s", k.Label)
+}
+
+// keyLabel returns the label of the key of a component before the key rotation
+// is applied. Components sharing a project share the key within the site as
+// well when the key scope is environment.
+func keyLabel(component string, cfg SiteComponentConfig) string {
+	if cfg.KeyScope == keyScopeEnvironment {
+		return "shared_" + helpers.Slugify(cfg.projectSlug(component))
+	}
+	return identifier(component)
+}
+
+// componentKey determines the key of a component in managed mode based on the
+// key scope of the component.
+func (p *SentryPlugin) componentKey(site, component string, cfg SiteComponentConfig) (sentryKey, error) {
+	scope := cfg.KeyScope
+	if scope == "" {
+		scope = keyScopeSite
+	}
+
+	// A shared key is named in the context of the owner site, so the other
+	// sites look up the key created by the owner, also when they map the
+	// environment differently.
+	nameSite := site
+	if scope != keyScopeSite {
+		if cfg.KeyOwnerSite == "" {
+			return sentryKey{}, fmt.Errorf("key_scope %s requires key_owner_site to be set", scope)
+		}
+		nameSite = cfg.KeyOwnerSite
+	}
+	nameSiteCfg, err := p.getSiteConfig(nameSite)
+	if err != nil {
+		return sentryKey{}, err
+	}
+	environment := nameSiteCfg.sentryEnvironment(nameSiteCfg.providerConfig(p.globalConfig), p.environment)
+
+	tpl := cfg.KeyNameTemplate
+	if tpl == "" {
+		tpl = defaultKeyNameTemplates[scope]
+	}
+
+	templateContext := struct {
+		Site        string
+		Component   string
+		Environment string
+		Project     string
+	}{
+		Site:        nameSite,
+		Component:   component,
+		Environment: environment,
+		Project:     cfg.projectSlug(component),
+	}
+	name, err := helpers.RenderGoTemplate(tpl, templateContext)
+	if err != nil {
+		return sentryKey{}, fmt.Errorf("invalid key_name_template: %w", err)
+	}
+
+	label := keyLabel(component, cfg)
+	if scope == keyScopeSite {
+		key := sentryKey{Label: label, Name: name, Managed: true, Render: true}
+		return key.rotate(cfg.KeyRotation), nil
+	}
+
+	owner := fmt.Sprintf("%s/%s", site, label)
+	if _, ok := p.sharedKeys[owner]; !ok {
+		p.sharedKeys[owner] = component
+	}
+
+	key := sentryKey{
+		Label:   label,
+		Name:    name,
+		Managed: cfg.KeyOwnerSite == site,
+		Render:  p.sharedKeys[owner] == component,
+	}
+	return key.rotate(cfg.KeyRotation), nil
+}
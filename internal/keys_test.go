@@ -0,0 +1,200 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComponentKeyScopes(t *testing.T) {
+	tests := []struct {
+		name   string
+		site   string
+		config SiteComponentConfig
+		key    sentryKey
+	}{
+		{
+			name:   "site scope",
+			site:   "my-site",
+			config: SiteComponentConfig{},
+			key:    sentryKey{Label: "my-component", Name: "prod-my-site-my-component", Managed: true, Render: true},
+		},
+		{
+			name: "custom name",
+			site: "my-site",
+			config: SiteComponentConfig{BaseConfig: BaseConfig{
+				KeyNameTemplate: "{{ .Component }}-{{ .Project }}",
+				Project:         "my-project",
+			}},
+			key: sentryKey{Label: "my-component", Name: "my-component-my-project", Managed: true, Render: true},
+		},
+		{
+			name: "component scope in owner site",
+			site: "my-site",
+			config: SiteComponentConfig{BaseConfig: BaseConfig{
+				KeyScope:     keyScopeComponent,
+				KeyOwnerSite: "my-site",
+			}},
+			key: sentryKey{Label: "my-component", Name: "prod-my-component", Managed: true, Render: true},
+		},
+		{
+			name: "component scope in other site",
+			site: "other-site",
+			config: SiteComponentConfig{BaseConfig: BaseConfig{
+				KeyScope:     keyScopeComponent,
+				KeyOwnerSite: "my-site",
+			}},
+			key: sentryKey{Label: "my-component", Name: "prod-my-component", Managed: false, Render: true},
+		},
+		{
+			name: "environment scope",
+			site: "my-site",
+			config: SiteComponentConfig{BaseConfig: BaseConfig{
+				KeyScope:     keyScopeEnvironment,
+				KeyOwnerSite: "my-site",
+				Project:      "my-project",
+			}},
+			key: sentryKey{Label: "shared_my_project", Name: "prod", Managed: true, Render: true},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := NewSentryPlugin()
+			p.Configure("prod", "")
+			key, err := p.componentKey(tc.site, "my-component", tc.config)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.key, key)
+		})
+	}
+}
+
+func TestComponentKeySharedWithinSite(t *testing.T) {
+	p := NewSentryPlugin()
+	p.Configure("prod", "")
+	cfg := SiteComponentConfig{BaseConfig: BaseConfig{
+		KeyScope:     keyScopeEnvironment,
+		KeyOwnerSite: "my-site",
+		Project:      "my-project",
+	}}
+
+	first, err := p.componentKey("my-site", "first", cfg)
+	assert.NoError(t, err)
+	assert.True(t, first.Render)
+
+	second, err := p.componentKey("my-site", "second", cfg)
+	assert.NoError(t, err)
+	assert.False(t, second.Render)
+	assert.Equal(t, first.address(), second.address())
+
+	// Rendering the first component again must render the key again
+	first, err = p.componentKey("my-site", "first", cfg)
+	assert.NoError(t, err)
+	assert.True(t, first.Render)
+}
+
+func TestComponentKeyRequiresOwnerSite(t *testing.T) {
+	p := NewSentryPlugin()
+	p.Configure("prod", "")
+	cfg := SiteComponentConfig{BaseConfig: BaseConfig{
+		KeyScope: keyScopeComponent,
+	}}
+
+	_, err := p.componentKey("my-site", "my-component", cfg)
+	assert.ErrorContains(t, err, "key_scope component requires key_owner_site to be set")
+}
+
+func TestComponentKeyRotation(t *testing.T) {
+	p := NewSentryPlugin()
+	p.Configure("prod", "")
+	cfg := SiteComponentConfig{BaseConfig: BaseConfig{
+		KeyRotation: &KeyRotation{Generation: 3, KeepGenerations: intPtr(2)},
+	}}
+
+	key, err := p.componentKey("my-site", "my-component", cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, "sentry_key.my-component_v3", key.address())
+	assert.Equal(t, "prod-my-site-my-component-v3", key.Name)
+
+	var labels []string
+	for _, generation := range key.Generations() {
+		labels = append(labels, generation.Label)
+	}
+	assert.Equal(t, []string{"my-component_v1", "my-component_v2", "my-component_v3"}, labels)
+}
+
+func TestComponentKeyRotationFirstGeneration(t *testing.T) {
+	p := NewSentryPlugin()
+	p.Configure("prod", "")
+	cfg := SiteComponentConfig{BaseConfig: BaseConfig{
+		KeyRotation: &KeyRotation{Generation: 1},
+	}}
+
+	key, err := p.componentKey("my-site", "my-component", cfg)
+	assert.NoError(t, err)
+	assert.Len(t, key.Previous, 1)
+	assert.Equal(t, "my-component", key.Previous[0].Label)
+	assert.Equal(t, "prod-my-site-my-component", key.Previous[0].Name)
+}
+
+func TestComponentKeyRotationSharedKey(t *testing.T) {
+	p := NewSentryPlugin()
+	p.Configure("prod", "")
+	cfg := SiteComponentConfig{BaseConfig: BaseConfig{
+		KeyScope:     keyScopeComponent,
+		KeyOwnerSite: "my-site",
+		KeyRotation:  &KeyRotation{Generation: 2},
+	}}
+
+	key, err := p.componentKey("other-site", "my-component", cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, "data.sentry_key.my-component_v2", key.address())
+	assert.Equal(t, "prod-my-component-v2", key.Name)
+	assert.Empty(t, key.Previous)
+}
+
+func TestComponentKeySharedNamedByOwnerSite(t *testing.T) {
+	p := NewSentryPlugin()
+	p.Configure("production", "")
+	p.SetGlobalConfig(map[string]any{
+		"environment_mapping": map[string]any{"production": "prod"},
+	})
+	p.SetSiteConfig("eu-1", map[string]any{
+		"environment_mapping": map[string]any{"production": "prod-eu"},
+	})
+	cfg := SiteComponentConfig{BaseConfig: BaseConfig{
+		KeyScope:     keyScopeComponent,
+		KeyOwnerSite: "us-1",
+	}}
+
+	owner, err := p.componentKey("us-1", "a", cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, "prod-a", owner.Name)
+
+	// The site maps the environment differently, but looks up the key created
+	// by the owner site.
+	other, err := p.componentKey("eu-1", "a", cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, "prod-a", other.Name)
+	assert.Equal(t, "data.sentry_key.a", other.address())
+}
+
+func TestComponentKeySharedCreatedProject(t *testing.T) {
+	p := NewSentryPlugin()
+	p.Configure("production", "")
+	cfg := SiteComponentConfig{BaseConfig: BaseConfig{
+		KeyScope:      keyScopeEnvironment,
+		KeyOwnerSite:  "my-site",
+		Project:       "shared",
+		CreateProject: boolPtr(true),
+	}}
+
+	first, err := p.componentKey("my-site", "a", cfg)
+	assert.NoError(t, err)
+	second, err := p.componentKey("my-site", "b", cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, "sentry_key.shared_shared", first.address())
+	assert.Equal(t, first.address(), second.address())
+	assert.True(t, first.Render)
+	assert.False(t, second.Render)
+}
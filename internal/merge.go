@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+
+	"dario.cat/mergo"
+)
+
+// mergeConfig merges layers of configuration, ordered from the lowest to the
+// highest precedence, into a new config. A setting of a layer overrides the
+// lower layers when it is set:
+//
+//   - strings are set when not empty,
+//   - pointers are set when not nil, so a pointer to false or 0 overrides a
+//     lower layer while nil inherits it,
+//   - slices are set when not nil and replace the lower layers, an empty list
+//     clears the inherited list,
+//   - maps are merged by key,
+//   - inbound filters are merged per filter.
+//
+// New settings follow these rules without changes to the merge.
+func mergeConfig(layers ...BaseConfig) (BaseConfig, error) {
+	result := BaseConfig{}
+	for _, layer := range layers {
+		err := mergo.Merge(&result, layer,
+			mergo.WithOverride,
+			mergo.WithoutDereference,
+			mergo.WithTransformers(configTransformers{}),
+		)
+		if err != nil {
+			return BaseConfig{}, fmt.Errorf("failed to merge config: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// configTransformers overrides how mergo merges pointers and slices which are
+// already set by a lower layer. By default mergo ignores pointers to structs
+// and empty slices.
+type configTransformers struct{}
+
+var inboundFiltersType = reflect.TypeOf(&InboundFilters{})
+
+func (configTransformers) Transformer(t reflect.Type) func(dst, src reflect.Value) error {
+	switch {
+	case t == inboundFiltersType:
+		return func(dst, src reflect.Value) error {
+			filters, ok := src.Interface().(*InboundFilters)
+			if !ok {
+				return fmt.Errorf("unexpected inbound filters type %s", src.Type())
+			}
+			inherited, ok := dst.Interface().(*InboundFilters)
+			if !ok {
+				return fmt.Errorf("unexpected inbound filters type %s", dst.Type())
+			}
+			dst.Set(reflect.ValueOf(filters.extend(inherited)))
+			return nil
+		}
+	case t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice:
+		return func(dst, src reflect.Value) error {
+			if !src.IsNil() {
+				dst.Set(src)
+			}
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+)
+
+type movedBlock struct {
+	From string
+	To   string
+}
+
+// movedResource is a resource whose label is the component label followed by
+// Suffix.
+type movedResource struct {
+	Type   string
+	Suffix string
+}
+
+// componentMoves returns the moved blocks for the resources of a renamed
+// component. The previous names are chained from oldest to newest, since
+// Terraform does not allow multiple moved blocks with the same destination.
+func componentMoves(component string, previousNames []string, resources []movedResource) []movedBlock {
+	names := append(slices.Clone(previousNames), component)
+
+	var result []movedBlock
+	for i := 0; i < len(names)-1; i++ {
+		from, to := names[i], names[i+1]
+		if from == to {
+			continue
+		}
+		for _, resource := range resources {
+			result = append(result, movedBlock{
+				From: fmt.Sprintf("%s.%s%s", resource.Type, from, resource.Suffix),
+				To:   fmt.Sprintf("%s.%s%s", resource.Type, to, resource.Suffix),
+			})
+		}
+	}
+	return result
+}
@@ -0,0 +1,30 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComponentMoves(t *testing.T) {
+	resources := []movedResource{
+		{Type: "sentry_project"},
+		{Type: "sentry_key", Suffix: "_v2"},
+		{Type: "sentry_issue_alert", Suffix: "_errors"},
+	}
+
+	result := componentMoves("my-component", []string{"legacy", "old-component"}, resources)
+	assert.Equal(t, []movedBlock{
+		{From: "sentry_project.legacy", To: "sentry_project.old-component"},
+		{From: "sentry_key.legacy_v2", To: "sentry_key.old-component_v2"},
+		{From: "sentry_issue_alert.legacy_errors", To: "sentry_issue_alert.old-component_errors"},
+		{From: "sentry_project.old-component", To: "sentry_project.my-component"},
+		{From: "sentry_key.old-component_v2", To: "sentry_key.my-component_v2"},
+		{From: "sentry_issue_alert.old-component_errors", To: "sentry_issue_alert.my-component_errors"},
+	}, result)
+}
+
+func TestComponentMovesWithoutPreviousNames(t *testing.T) {
+	result := componentMoves("my-component", nil, []movedResource{{Type: "sentry_project"}})
+	assert.Empty(t, result)
+}
@@ -1,12 +1,16 @@
 package internal
 
 import (
+	"bytes"
 	"embed"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"sync"
 
-	_ "dario.cat/mergo"
 	"github.com/hashicorp/go-hclog"
 	"github.com/mach-composer/mach-composer-plugin-helpers/helpers"
 	"github.com/mach-composer/mach-composer-plugin-sdk/v2/schema"
@@ -20,18 +24,33 @@ var templates embed.FS
 var schemas embed.FS
 
 type SentryPlugin struct {
-	environment      string
-	provider         string
-	globalConfig     GlobalConfig
+	environment  string
+	provider     string
+	globalConfig GlobalConfig
+	// globalLayer holds the global settings without defaults, to tell apart
+	// configured settings from defaults.
+	globalLayer      BaseConfig
 	siteConfigs      map[string]SiteConfig
 	componentConfigs map[string]ComponentConfig
+
+	// sharedKeys tracks which component renders a key shared between
+	// components of a site.
+	sharedKeys map[string]string
+	// sharedProjects tracks which component renders a project shared between
+	// components of a site.
+	sharedProjects map[string]sharedProject
+	// renderedAddresses tracks which component renders each Terraform address
+	// of a site.
+	renderedAddresses map[string]string
 }
 
 func NewSentryPlugin() *SentryPlugin {
 	state := &SentryPlugin{
-		provider:         "1.0.2",
-		siteConfigs:      map[string]SiteConfig{},
-		componentConfigs: map[string]ComponentConfig{},
+		siteConfigs:       map[string]SiteConfig{},
+		componentConfigs:  map[string]ComponentConfig{},
+		sharedKeys:        map[string]string{},
+		sharedProjects:    map[string]sharedProject{},
+		renderedAddresses: map[string]string{},
 	}
 
 	return state
@@ -45,8 +64,38 @@ func (p *SentryPlugin) Configure(environment string, provider string) error {
 	return nil
 }
 
+// IsEnabled returns whether the global configuration runs in managed mode.
 func (p *SentryPlugin) IsEnabled() bool {
-	return p.globalConfig.AuthToken != ""
+	return p.globalConfig.mode(p.globalConfig) == modeManaged
+}
+
+// usesProvider returns whether the site or any of its components runs in
+// managed or lookup mode, in which case the site needs the Sentry provider.
+func (p *SentryPlugin) usesProvider(site string) (bool, error) {
+	return p.siteHasMode(site, modeManaged, modeLookup)
+}
+
+// siteHasMode returns whether the site or any of its components runs in one of
+// the given modes.
+func (p *SentryPlugin) siteHasMode(site string, modes ...string) (bool, error) {
+	siteCfg, err := p.getSiteConfig(site)
+	if err != nil {
+		return false, err
+	}
+	globalCfg := siteCfg.providerConfig(p.globalConfig)
+	if siteCfg.enabled() && slices.Contains(modes, siteCfg.mode(globalCfg)) {
+		return true, nil
+	}
+	for name := range siteCfg.Components {
+		cfg, err := siteCfg.getSiteComponentConfig(name)
+		if err != nil {
+			return false, err
+		}
+		if cfg.enabled() && slices.Contains(modes, cfg.mode(globalCfg)) {
+			return true, nil
+		}
+	}
+	return false, nil
 }
 
 func (p *SentryPlugin) GetValidationSchema() (*schema.ValidationSchema, error) {
@@ -64,6 +113,10 @@ func (p *SentryPlugin) GetValidationSchema() (*schema.ValidationSchema, error) {
 		return nil, err
 	}
 
+	if err := loadSchemaNode("schemas/component-config.json", &s.ComponentConfigSchema); err != nil {
+		return nil, err
+	}
+
 	return s, nil
 }
 
@@ -76,7 +129,12 @@ func (p *SentryPlugin) SetGlobalConfig(data map[string]any) error {
 	if err := mapstructure.Decode(data, &cfg); err != nil {
 		return err
 	}
+	layer := GlobalConfig{}
+	if err := mapstructure.Decode(data, &layer); err != nil {
+		return err
+	}
 	p.globalConfig = cfg
+	p.globalLayer = layer.BaseConfig
 	return nil
 }
 
@@ -89,6 +147,10 @@ func (p *SentryPlugin) SetSiteConfig(site string, data map[string]any) error {
 	if err := mapstructure.Decode(data, &cfg); err != nil {
 		return err
 	}
+	// Keep the components registered for the site before its config was set.
+	if existing, ok := p.siteConfigs[site]; ok {
+		cfg.Components = existing.Components
+	}
 	p.siteConfigs[site] = cfg
 	return nil
 }
@@ -114,6 +176,18 @@ func (p *SentryPlugin) SetSiteComponentConfig(site string, component string, dat
 }
 
 func (p *SentryPlugin) SetComponentConfig(component, version string, data map[string]any) error {
+	if err := validate("schemas/component-config.json", data); err != nil {
+		return fmt.Errorf("invalid component config for component %s: %w", component, err)
+	}
+
+	// Resource labels are derived from the component name, so two components
+	// mapping to the same identifier would render conflicting resources.
+	for other := range p.componentConfigs {
+		if other != component && identifier(other) == identifier(component) {
+			return fmt.Errorf("components %s and %s map to the same Terraform identifier %s", other, component, identifier(component))
+		}
+	}
+
 	cfg := ComponentConfig{
 		Version: version,
 	}
@@ -126,34 +200,196 @@ func (p *SentryPlugin) SetComponentConfig(component, version string, data map[st
 	return nil
 }
 
-func (p *SentryPlugin) RenderTerraformProviders(_ string) (string, error) {
-	if !p.IsEnabled() {
-		hclog.Default().Warn("Sentry plugin provider rendering is disabled. Set auth_token to enable")
+func (p *SentryPlugin) RenderTerraformProviders(site string) (string, error) {
+	// The providers are rendered first for every site, so all configuration
+	// problems are reported before anything is rendered.
+	if err := p.Validate(); err != nil {
+		return "", fmt.Errorf("invalid sentry config: %w", err)
+	}
+
+	usesProvider, err := p.usesProvider(site)
+	if err != nil {
+		return "", err
+	}
+	if !usesProvider {
+		hclog.Default().Warn("Sentry plugin provider rendering is disabled. Set auth_token or mode to enable", "site", site)
 		return "", nil
 	}
+	backend, err := getProviderBackend(p.globalConfig.ProviderSource)
+	if err != nil {
+		return "", err
+	}
+
 	result := fmt.Sprintf(`
 		sentry = {
-			source = "labd/sentry"
+			source = "%s"
 			version = "%s"
-		}`, helpers.VersionConstraint(p.provider))
+		}`, backend.source, helpers.VersionConstraint(p.providerVersion(backend)))
 	return result, nil
 }
 
-func (p *SentryPlugin) RenderTerraformResources(_ string) (string, error) {
-	if !p.IsEnabled() {
-		hclog.Default().Warn("Sentry plugin resource rendering is disabled. Set auth_token to enable")
+// providerVersion returns the provider version configured in mach-composer,
+// falling back to the default version of the backend.
+func (p *SentryPlugin) providerVersion(backend providerBackend) string {
+	if p.provider == "" {
+		return backend.defaultVersion
+	}
+	return p.provider
+}
+
+func (p *SentryPlugin) RenderTerraformResources(site string) (string, error) {
+	usesProvider, err := p.usesProvider(site)
+	if err != nil {
+		return "", err
+	}
+	if !usesProvider {
+		hclog.Default().Warn("Sentry plugin resource rendering is disabled. Set auth_token or mode to enable", "site", site)
 		return "", nil
 	}
 
+	siteCfg, err := p.getSiteConfig(site)
+	if err != nil {
+		return "", err
+	}
+	globalCfg := siteCfg.providerConfig(p.globalConfig)
+	provider := providerResource{
+		Alias: siteCfg.providerAlias(site),
+		Token: globalCfg.AuthToken,
+		URL:   globalCfg.BaseURL,
+	}
+
+	tpl, err := templates.ReadFile("templates/provider.tmpl")
+	if err != nil {
+		return "", err
+	}
+
+	result, err := helpers.RenderGoTemplate(string(tpl), provider)
+	if err != nil {
+		return "", err
+	}
+
+	managed, err := p.siteHasMode(site, modeManaged)
+	if err != nil {
+		return "", err
+	}
+	if managed {
+		teams, err := terraformRenderTeams(teamResources(site, globalCfg), globalCfg, provider.Alias)
+		if err != nil {
+			return "", err
+		}
+		result += teams
+	}
+
+	if err := validateHCL(result, "sentry_resources.tf"); err != nil {
+		return "", fmt.Errorf("invalid Terraform rendered for site %s: %w", site, err)
+	}
+
+	return result, nil
+}
+
+// RenderTerraformJSON renders the provider, the site resources and the
+// resources of the given components of a site as a Terraform JSON
+// configuration. The output is generated from the same model as the HCL
+// templates. Component variables are passed to the component modules by
+// mach-composer and are not part of the output.
+func (p *SentryPlugin) RenderTerraformJSON(site string, components []string) ([]byte, error) {
+	result := newTerraformJSON()
+
+	usesProvider, err := p.usesProvider(site)
+	if err != nil {
+		return nil, err
+	}
+	if usesProvider {
+		backend, err := getProviderBackend(p.globalConfig.ProviderSource)
+		if err != nil {
+			return nil, err
+		}
+
+		siteCfg, err := p.getSiteConfig(site)
+		if err != nil {
+			return nil, err
+		}
+		globalCfg := siteCfg.providerConfig(p.globalConfig)
+		provider := providerResource{
+			Alias: siteCfg.providerAlias(site),
+			Token: globalCfg.AuthToken,
+			URL:   globalCfg.BaseURL,
+		}
+		result.addProvider(backend, helpers.VersionConstraint(p.providerVersion(backend)), provider)
+
+		managed, err := p.siteHasMode(site, modeManaged)
+		if err != nil {
+			return nil, err
+		}
+		if managed {
+			result.addTeams(teamResources(site, globalCfg), globalCfg, provider.Alias)
+		}
+	}
+
+	for _, component := range components {
+		plan, err := p.planComponent(site, component)
+		if err != nil {
+			return nil, err
+		}
+		if plan.Lookup != nil {
+			result.addLookup(*plan.Lookup)
+		}
+		if plan.Resources != nil {
+			result.addComponentResources(*plan.Resources)
+		}
+	}
+
+	// Provider version constraints contain characters escaped by default.
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// providerResource is the configuration of the Sentry provider of a site.
+type providerResource struct {
+	Alias string
+	Token string
+	URL   string
+}
+
+type teamResource struct {
+	Team
+	Label   string
+	Managed bool
+}
+
+// teamResources returns the teams to render in a site. Teams owned by another
+// site are read with a data source instead.
+func teamResources(site string, globalCfg GlobalConfig) []teamResource {
+	teams := make([]teamResource, 0, len(globalCfg.Teams))
+	for _, team := range globalCfg.Teams {
+		teams = append(teams, teamResource{
+			Team:    team,
+			Label:   teamLabel(team.Slug),
+			Managed: team.Site == "" || team.Site == site,
+		})
+	}
+	return teams
+}
+
+// terraformRenderTeams renders the teams of a site.
+func terraformRenderTeams(teams []teamResource, globalCfg GlobalConfig, providerAlias string) (string, error) {
 	templateContext := struct {
-		Token string
-		URL   string
+		Teams         []teamResource
+		ProviderAlias string
+		Global        GlobalConfig
 	}{
-		Token: p.globalConfig.AuthToken,
-		URL:   p.globalConfig.BaseURL,
+		Teams:         teams,
+		ProviderAlias: providerAlias,
+		Global:        globalCfg,
 	}
 
-	tpl, err := templates.ReadFile("templates/provider.tmpl")
+	tpl, err := templates.ReadFile("templates/teams.tmpl")
 	if err != nil {
 		return "", err
 	}
@@ -161,25 +397,127 @@ func (p *SentryPlugin) RenderTerraformResources(_ string) (string, error) {
 	return helpers.RenderGoTemplate(string(tpl), templateContext)
 }
 
+// teamReference returns the expression referring to the team with the given
+// slug, pointing to the team resource or data source when the team is managed
+// by the plugin.
+func teamReference(site string, globalCfg GlobalConfig, slug string) string {
+	team, ok := globalCfg.getTeam(slug)
+	if !ok {
+		return fmt.Sprintf("%q", slug)
+	}
+	if team.Site == "" || team.Site == site {
+		return fmt.Sprintf("sentry_team.%s.slug", teamLabel(slug))
+	}
+	return fmt.Sprintf("data.sentry_team.%s.slug", teamLabel(slug))
+}
+
+func teamLabel(slug string) string {
+	return "team_" + helpers.Slugify(slug)
+}
+
 var (
-	warnOnce sync.Once
+	warnOnce            sync.Once
+	deploymentsWarnOnce sync.Once
 )
 
+// componentPlan is the typed model of everything the plugin renders for a
+// component. Both the HCL templates and the JSON output are generated from it.
+type componentPlan struct {
+	Variables []string
+	// Lookup is set when the component reads an existing key.
+	Lookup *lookupResource
+	// Resources is set when the plugin manages the Sentry resources of the
+	// component.
+	Resources *componentResources
+}
+
 func (p *SentryPlugin) RenderTerraformComponent(site string, component string) (*schema.ComponentSchema, error) {
-	siteComponentConfig := p.getSiteComponentConfig(site, component)
-	componentConfig, err := p.getComponentConfig(component)
+	plan, err := p.planComponent(site, component)
 	if err != nil {
 		return nil, err
 	}
 
+	result := &schema.ComponentSchema{
+		Variables: strings.Join(plan.Variables, "\n"),
+	}
+
+	var resources string
+	switch {
+	case plan.Lookup != nil:
+		resources, err = terraformRenderComponentLookup(*plan.Lookup)
+	case plan.Resources != nil:
+		resources, err = terraformRenderComponentResources(*plan.Resources)
+	default:
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := validateHCL(resources, fmt.Sprintf("sentry_%s.tf", identifier(component))); err != nil {
+		return nil, fmt.Errorf("invalid Terraform rendered for component %s in site %s: %w", component, site, err)
+	}
+	result.Resources = resources
+
+	return result, nil
+}
+
+// planComponent resolves the configuration of a component in a site into the
+// variables and resources to render.
+func (p *SentryPlugin) planComponent(site string, component string) (componentPlan, error) {
+	siteCfg, err := p.getSiteConfig(site)
+	if err != nil {
+		return componentPlan{}, err
+	}
+	siteComponentConfig, err := siteCfg.getSiteComponentConfig(component)
+	if err != nil {
+		return componentPlan{}, fmt.Errorf("invalid config for component %s in site %s: %w", component, site, err)
+	}
+	componentConfig, err := p.getComponentConfig(component)
+	if err != nil {
+		return componentPlan{}, err
+	}
+
+	if !siteComponentConfig.enabled() {
+		return componentPlan{Variables: disabledVariables(siteComponentConfig)}, nil
+	}
+
+	globalCfg := siteCfg.providerConfig(p.globalConfig)
+	providerAlias := siteCfg.providerAlias(site)
+	backend, err := getProviderBackend(globalCfg.ProviderSource)
+	if err != nil {
+		return componentPlan{}, err
+	}
+
+	if errs := siteComponentConfig.validate(globalCfg); len(errs) > 0 {
+		return componentPlan{}, fmt.Errorf("invalid config for component %s in site %s: %w", component, site, errors.Join(errs...))
+	}
+	mode := siteComponentConfig.mode(globalCfg)
+
+	// The release and environment are passed as-is to the sentry_release_deployment
+	// so the events of the component match the tracked deployment.
+	environment := siteCfg.sentryEnvironment(globalCfg, p.environment)
+	release, err := releaseName(siteComponentConfig.ReleaseNameTemplate, site, component, componentConfig.Version, environment)
+	if err != nil {
+		return componentPlan{}, fmt.Errorf("invalid release_name_template for component %s in site %s: %w", component, site, err)
+	}
+
+	label := identifier(component)
+	key := sentryKey{Label: label}
+	if mode == modeManaged {
+		key, err = p.componentKey(site, component, siteComponentConfig)
+		if err != nil {
+			return componentPlan{}, fmt.Errorf("invalid config for component %s in site %s: %w", component, site, err)
+		}
+	}
+
 	var vars []string
-	if p.globalConfig.AuthToken != "" {
+	if mode != modeUnmanaged {
 		vars = append(vars,
-			fmt.Sprintf("sentry_dsn = sentry_key.%s.dsn_secret", component),
+			fmt.Sprintf("sentry_dsn = %s.%s", key.address(), backend.dsnAttribute),
 		)
 		if siteComponentConfig.ExposeKey != nil && *siteComponentConfig.ExposeKey {
 			vars = append(vars,
-				fmt.Sprintf("sentry_key = sentry_key.%s.secret", component),
+				fmt.Sprintf("sentry_key = %s.%s", key.address(), backend.secretAttribute),
 			)
 		}
 	} else {
@@ -187,42 +525,232 @@ func (p *SentryPlugin) RenderTerraformComponent(site string, component string) (
 			fmt.Sprintf("sentry_dsn = \"%s\"", siteComponentConfig.DSN),
 		)
 		if siteComponentConfig.ExposeKey != nil && *siteComponentConfig.ExposeKey {
-			hclog.Default().Warn("expose_key is set to true but auth_token is not configured; sentry_key will not be available", "site", site, "component", component)
+			hclog.Default().Warn("expose_key is set to true but the component is in unmanaged mode; sentry_key will not be available", "site", site, "component", component)
 		}
 	}
 
-	result := &schema.ComponentSchema{
-		Variables: strings.Join(vars, "\n"),
+	if siteComponentConfig.ExposeRelease != nil && *siteComponentConfig.ExposeRelease {
+		vars = append(vars,
+			fmt.Sprintf("sentry_environment = %q", environment),
+			fmt.Sprintf("sentry_release = %q", release),
+		)
 	}
 
-	if !p.IsEnabled() {
-		warnOnce.Do(func() {
-			hclog.Default().Warn("Sentry plugin component rendering is disabled. Set auth_token to enable")
-		})
-		return result, nil
+	plan := componentPlan{Variables: vars}
+
+	switch mode {
+	case modeUnmanaged:
+		if siteComponentConfig.Mode == "" {
+			warnOnce.Do(func() {
+				hclog.Default().Warn("Sentry plugin component rendering is disabled. Set auth_token or mode to enable")
+			})
+		}
+	case modeLookup:
+		plan.Lookup = &lookupResource{
+			Label:         label,
+			ProviderAlias: providerAlias,
+			Global:        globalCfg,
+			Config:        siteComponentConfig,
+		}
+	default:
+		project, err := p.componentProject(site, component, siteComponentConfig)
+		if err != nil {
+			return componentPlan{}, fmt.Errorf("invalid config for component %s in site %s: %w", component, site, err)
+		}
+		resources, err := newComponentResources(backend, site, component, release, environment, project, key, componentConfig.PreviousNames, globalCfg, providerAlias, siteComponentConfig)
+		if err != nil {
+			return componentPlan{}, err
+		}
+		plan.Resources = &resources
 	}
 
-	resources, err := terraformRenderComponentResources(site, component, componentConfig.Version, p.environment, p.globalConfig, siteComponentConfig)
-	if err != nil {
-		return nil, err
+	if err := p.claimAddresses(site, component, plan.addresses()); err != nil {
+		return componentPlan{}, err
 	}
-	result.Resources = resources
 
-	return result, nil
+	return plan, nil
+}
+
+// addresses returns the Terraform addresses of the resources and data sources
+// rendered for the component.
+func (c componentPlan) addresses() []string {
+	if c.Lookup != nil {
+		return []string{fmt.Sprintf("data.sentry_key.%s", c.Lookup.Label)}
+	}
+	if c.Resources == nil {
+		return nil
+	}
+
+	r := c.Resources
+	var result []string
+	if r.CreateProject && r.RenderProject {
+		if r.ProjectManaged {
+			result = append(result, fmt.Sprintf("sentry_project.%s", r.ProjectLabel))
+		} else {
+			result = append(result, fmt.Sprintf("data.sentry_project.%s", r.ProjectLabel))
+		}
+	}
+	if r.TrackDeployments {
+		result = append(result, fmt.Sprintf("sentry_release_deployment.%s", r.ComponentLabel))
+	}
+	if r.Key.Render {
+		for _, generation := range r.Key.Generations() {
+			result = append(result, generation.address())
+		}
+	}
+	if r.SpikeProtection != nil {
+		result = append(result, fmt.Sprintf("sentry_project_spike_protection.%s", r.ProjectLabel))
+	}
+	for _, filter := range r.InboundFilters {
+		result = append(result, fmt.Sprintf("sentry_project_inbound_data_filter.%s", filter.Label))
+	}
+	for _, alert := range r.Alerts {
+		result = append(result, fmt.Sprintf("sentry_issue_alert.%s", alert.Label))
+	}
+	for _, alert := range r.MetricAlerts {
+		result = append(result, fmt.Sprintf("sentry_metric_alert.%s", alert.Label))
+	}
+	return result
+}
+
+// claimAddresses records the addresses rendered by a component in a site.
+// Labels are derived from sanitized names, so different components may end up
+// with the same address, which Terraform would reject or, in JSON output,
+// silently overwrite.
+func (p *SentryPlugin) claimAddresses(site, component string, addresses []string) error {
+	for _, address := range addresses {
+		owner := fmt.Sprintf("%s/%s", site, address)
+		if other, ok := p.renderedAddresses[owner]; ok && other != component {
+			return fmt.Errorf("components %s and %s both render %s in site %s", other, component, address, site)
+		}
+	}
+	for _, address := range addresses {
+		p.renderedAddresses[fmt.Sprintf("%s/%s", site, address)] = component
+	}
+	return nil
 }
 
-func (p *SentryPlugin) getSiteConfig(site string) SiteConfig {
+// Validate checks the merged configuration of the components registered for
+// every site and reports all problems at once, prefixed with the path of the
+// site and component.
+func (p *SentryPlugin) Validate() error {
+	var errs []error
+
+	// A project can only be managed by a single site, so projects managed by
+	// multiple sites require an owner site.
+	var projects []string
+	projectSites := map[string][]string{}
+	projectPaths := map[string][]string{}
+
+	for _, site := range slices.Sorted(maps.Keys(p.siteConfigs)) {
+		siteCfg, err := p.getSiteConfig(site)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("sites.%s: %w", site, err))
+			continue
+		}
+		globalCfg := siteCfg.providerConfig(p.globalConfig)
+
+		for _, component := range slices.Sorted(maps.Keys(siteCfg.Components)) {
+			path := fmt.Sprintf("sites.%s.components.%s", site, component)
+			cfg, err := siteCfg.getSiteComponentConfig(component)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", path, err))
+				continue
+			}
+			if !cfg.enabled() {
+				continue
+			}
+			for _, err := range cfg.validate(globalCfg) {
+				errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			}
+
+			if cfg.mode(globalCfg) != modeManaged || !cfg.hasProjectSettings() || cfg.ProjectOwnerSite != "" {
+				continue
+			}
+			project := fmt.Sprintf("%s/%s", globalCfg.Organization, cfg.projectSlug(component))
+			if _, ok := projectSites[project]; !ok {
+				projects = append(projects, project)
+			}
+			if !slices.Contains(projectSites[project], site) {
+				projectSites[project] = append(projectSites[project], site)
+			}
+			projectPaths[project] = append(projectPaths[project], path)
+		}
+	}
+
+	for _, project := range projects {
+		if len(projectSites[project]) < 2 {
+			continue
+		}
+		for _, path := range projectPaths[project] {
+			errs = append(errs, fmt.Errorf("%s: project %s is managed by sites %s, which requires project_owner_site to be set",
+				path, project, strings.Join(projectSites[project], ", ")))
+		}
+	}
+
+	// Teams without a site are created by every managed site, so a team can
+	// only be left without a site when a single site manages the organization.
+	var organizations []string
+	organizationSites := map[string][]string{}
+	for _, site := range slices.Sorted(maps.Keys(p.siteConfigs)) {
+		// Merge errors are reported by the component checks above.
+		managed, err := p.siteHasMode(site, modeManaged)
+		if err != nil || !managed {
+			continue
+		}
+		siteCfg, err := p.getSiteConfig(site)
+		if err != nil {
+			continue
+		}
+		organization := siteCfg.providerConfig(p.globalConfig).Organization
+		if _, ok := organizationSites[organization]; !ok {
+			organizations = append(organizations, organization)
+		}
+		organizationSites[organization] = append(organizationSites[organization], site)
+	}
+	for _, team := range p.globalConfig.Teams {
+		if team.Site != "" {
+			continue
+		}
+		for _, organization := range organizations {
+			if len(organizationSites[organization]) < 2 {
+				continue
+			}
+			errs = append(errs, fmt.Errorf("teams.%s: team %s is managed by sites %s of organization %s, which requires site to be set",
+				team.Slug, team.Slug, strings.Join(organizationSites[organization], ", "), organization))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// disabledVariables returns the variables of a disabled component according
+// to the disabled_variables policy.
+func disabledVariables(cfg SiteComponentConfig) []string {
+	if cfg.DisabledVariables == disabledVariablesNone {
+		return nil
+	}
+
+	vars := []string{`sentry_dsn = ""`}
+	if cfg.ExposeKey != nil && *cfg.ExposeKey {
+		vars = append(vars, `sentry_key = ""`)
+	}
+	if cfg.ExposeRelease != nil && *cfg.ExposeRelease {
+		vars = append(vars, `sentry_environment = ""`, `sentry_release = ""`)
+	}
+	return vars
+}
+
+func (p *SentryPlugin) getSiteConfig(site string) (SiteConfig, error) {
 	cfg, ok := p.siteConfigs[site]
 	if !ok {
 		cfg = newSiteConfig()
 	}
-	return cfg.extendGlobalConfig(p.globalConfig)
-}
-
-func (p *SentryPlugin) getSiteComponentConfig(site, name string) SiteComponentConfig {
-	siteCfg := p.getSiteConfig(site)
-	cfg := siteCfg.getSiteComponentConfig(name)
-	return cfg
+	base, err := mergeLayers(p.configLayers(site, ""))
+	if err != nil {
+		return SiteConfig{}, fmt.Errorf("invalid config for site %s: %w", site, err)
+	}
+	cfg.BaseConfig = base
+	return cfg, nil
 }
 
 func (p *SentryPlugin) getComponentConfig(component string) (ComponentConfig, error) {
@@ -233,35 +761,369 @@ func (p *SentryPlugin) getComponentConfig(component string) (ComponentConfig, er
 	return cfg, nil
 }
 
-func terraformRenderComponentResources(site, component, componentVersion, environment string, globalCfg GlobalConfig,
-	cfg SiteComponentConfig) (string, error) {
+// componentResources holds the Sentry resources of a managed component.
+type componentResources struct {
+	SiteName       string
+	ComponentName  string
+	ComponentLabel string
+	Release        string
+	Key            sentryKey
+	// ImportKeyLabel is the label of the key key_id is imported into, empty
+	// when the original key is no longer kept after a key rotation.
+	ImportKeyLabel   string
+	Environment      string
+	TrackDeployments bool
+	CreateProject    bool
+	// Project and Team are Terraform expressions, either a quoted slug or a
+	// reference to a resource managed by the plugin.
+	Project        string
+	ProjectLabel   string
+	ProjectSlug    string
+	ProjectName    string
+	ProjectManaged bool
+	RenderProject  bool
+	Team           string
+	InboundFilters []inboundFilterResource
+	ProjectFilters bool
+	// SpikeProtection is only set when the site manages the project.
+	SpikeProtection *bool
+	Alerts          []issueAlertResource
+	MetricAlerts    []metricAlertResource
+	Moved           []movedBlock
+	ProviderAlias   string
+	Global          GlobalConfig
+	Config          SiteComponentConfig
+
+	backend providerBackend
+}
+
+func newComponentResources(backend providerBackend, site, component, release, environment string,
+	sentryProject sentryProject, key sentryKey, previousNames []string, globalCfg GlobalConfig, providerAlias string, cfg SiteComponentConfig) (componentResources, error) {
 	trackDeployments := false
 	if cfg.TrackDeployments != nil {
 		trackDeployments = *cfg.TrackDeployments
 	}
+	if trackDeployments && !backend.releaseDeployments {
+		deploymentsWarnOnce.Do(func() {
+			hclog.Default().Warn("track_deployments is not supported by the configured provider and is ignored", "provider", backend.source)
+		})
+		trackDeployments = false
+	}
 
-	templateContext := struct {
-		SiteName         string
-		ComponentName    string
-		ComponentVersion string
-		Environment      string
-		TrackDeployments bool
-		Global           GlobalConfig
-		Config           SiteComponentConfig
-	}{
+	createProject := cfg.createProject()
+
+	// Component names are not necessarily valid Terraform identifiers, so
+	// resource labels are derived from a sanitized name.
+	label := identifier(component)
+	team := teamReference(site, globalCfg, cfg.Team)
+	if cfg.Team != "" && !createProject {
+		hclog.Default().Warn("team is only assigned to projects created with create_project and is ignored", "site", site, "component", component)
+	}
+
+	projectFilters := cfg.InboundFilters != nil && (cfg.InboundFilters.ErrorMessages != nil || cfg.InboundFilters.Releases != nil)
+	if projectFilters && !createProject {
+		hclog.Default().Warn("inbound_filters error_messages and releases require create_project and are ignored", "site", site, "component", component)
+	}
+
+	// Project settings are rendered once per project, by the site managing the
+	// project.
+	var (
+		inboundFilters  []inboundFilterResource
+		spikeProtection *bool
+		alerts          []issueAlertResource
+		metricAlerts    []metricAlertResource
+	)
+	if sentryProject.Managed && sentryProject.Render {
+		inboundFilters = inboundFilterResources(sentryProject.Label, cfg.InboundFilters)
+		spikeProtection = cfg.SpikeProtection
+
+		var err error
+		alerts, err = issueAlertResources(sentryProject.Label, cfg.Alerts)
+		if err != nil {
+			return componentResources{}, fmt.Errorf("invalid alerts for component %s in site %s: %w", component, site, err)
+		}
+		metricAlerts, err = metricAlertResources(sentryProject.Label, cfg.MetricAlerts)
+		if err != nil {
+			return componentResources{}, fmt.Errorf("invalid metric_alerts for component %s in site %s: %w", component, site, err)
+		}
+	}
+
+	// Collect the resources labelled after the component so they can be moved
+	// when the component is renamed. Project resources are labelled after the
+	// project, which only follows the component name when the project slug
+	// defaults to it.
+	var resources []movedResource
+	if trackDeployments {
+		resources = append(resources, movedResource{Type: "sentry_release_deployment"})
+	}
+	if key.Managed && key.Render && keyLabel(component, cfg) == label {
+		for _, generation := range key.Generations() {
+			resources = append(resources, movedResource{Type: "sentry_key", Suffix: generationSuffix(generation.Generation)})
+		}
+	}
+	if cfg.Project == "" && sentryProject.Label == label {
+		if createProject && sentryProject.Managed && sentryProject.Render {
+			resources = append(resources, movedResource{Type: "sentry_project"})
+		}
+		if spikeProtection != nil {
+			resources = append(resources, movedResource{Type: "sentry_project_spike_protection"})
+		}
+		for _, filter := range inboundFilters {
+			resources = append(resources, movedResource{
+				Type:   "sentry_project_inbound_data_filter",
+				Suffix: strings.TrimPrefix(filter.Label, label),
+			})
+		}
+		for _, alert := range alerts {
+			resources = append(resources, movedResource{
+				Type:   "sentry_issue_alert",
+				Suffix: strings.TrimPrefix(alert.Label, label),
+			})
+		}
+		for _, alert := range metricAlerts {
+			resources = append(resources, movedResource{
+				Type:   "sentry_metric_alert",
+				Suffix: strings.TrimPrefix(alert.Label, label),
+			})
+		}
+	}
+	previousLabels := make([]string, len(previousNames))
+	for i, name := range previousNames {
+		previousLabels[i] = identifier(name)
+	}
+	moved := componentMoves(label, previousLabels, resources)
+
+	// An existing key is imported into, and a key which became shared through
+	// the key scope takes over, the original generation of the key. Rotated
+	// generations are new keys.
+	var importKeyLabel string
+	if original, ok := key.original(); ok && key.Managed && key.Render {
+		if cfg.KeyID != "" {
+			importKeyLabel = original.Label
+		}
+		if original.Label != label {
+			moved = append(moved, movedBlock{
+				From: fmt.Sprintf("sentry_key.%s", label),
+				To:   original.address(),
+			})
+		}
+	}
+
+	return componentResources{
 		SiteName:         site,
 		ComponentName:    component,
-		ComponentVersion: componentVersion,
+		ComponentLabel:   label,
+		Release:          release,
+		Key:              key,
+		ImportKeyLabel:   importKeyLabel,
 		Environment:      environment,
 		TrackDeployments: trackDeployments,
+		CreateProject:    createProject,
+		Project:          sentryProject.reference(),
+		ProjectLabel:     sentryProject.Label,
+		ProjectSlug:      sentryProject.Slug,
+		ProjectName:      sentryProject.Name,
+		ProjectManaged:   sentryProject.Managed,
+		RenderProject:    sentryProject.Render,
+		Team:             team,
+		InboundFilters:   inboundFilters,
+		ProjectFilters:   projectFilters,
+		SpikeProtection:  spikeProtection,
+		Alerts:           alerts,
+		MetricAlerts:     metricAlerts,
+		Moved:            moved,
+		ProviderAlias:    providerAlias,
 		Global:           globalCfg,
 		Config:           cfg,
+		backend:          backend,
+	}, nil
+}
+
+func terraformRenderComponentResources(resources componentResources) (string, error) {
+	tpl, err := templates.ReadFile(resources.backend.resourcesTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	return helpers.RenderGoTemplate(string(tpl), resources)
+}
+
+// releaseName renders the name of the release of a component. Without a
+// template the component version is used.
+func releaseName(tpl, site, component, version, environment string) (string, error) {
+	if tpl == "" {
+		return version, nil
 	}
 
-	tpl, err := templates.ReadFile("templates/resources.tmpl")
+	templateContext := struct {
+		Site        string
+		Component   string
+		Version     string
+		Environment string
+	}{
+		Site:        site,
+		Component:   component,
+		Version:     version,
+		Environment: environment,
+	}
+	return helpers.RenderGoTemplate(tpl, templateContext)
+}
+
+// lookupResource is the data source reading the existing Sentry key of a
+// component in lookup mode.
+type lookupResource struct {
+	Label         string
+	ProviderAlias string
+	Global        GlobalConfig
+	Config        SiteComponentConfig
+}
+
+// terraformRenderComponentLookup renders a data source reading the existing
+// Sentry key of the component instead of managing it.
+func terraformRenderComponentLookup(lookup lookupResource) (string, error) {
+	tpl, err := templates.ReadFile("templates/lookup.tmpl")
 	if err != nil {
 		return "", err
 	}
 
-	return helpers.RenderGoTemplate(string(tpl), templateContext)
+	return helpers.RenderGoTemplate(string(tpl), lookup)
+}
+
+type inboundFilterResource struct {
+	Label      string
+	FilterID   string
+	Active     bool
+	Subfilters []string
+}
+
+// inboundFilterResources returns the inbound data filters to render for a
+// project. Error message and release filters are project options and are
+// rendered as part of the project instead.
+func inboundFilterResources(project string, filters *InboundFilters) []inboundFilterResource {
+	if filters == nil {
+		return nil
+	}
+
+	var result []inboundFilterResource
+	toggles := []struct {
+		id     string
+		active *bool
+	}{
+		{"browser-extensions", filters.BrowserExtensions},
+		{"localhost", filters.Localhost},
+		{"web-crawlers", filters.WebCrawlers},
+	}
+	for _, toggle := range toggles {
+		if toggle.active == nil {
+			continue
+		}
+		result = append(result, inboundFilterResource{
+			Label:    fmt.Sprintf("%s_%s", project, helpers.Slugify(toggle.id)),
+			FilterID: toggle.id,
+			Active:   *toggle.active,
+		})
+	}
+
+	if filters.LegacyBrowsers != nil {
+		result = append(result, inboundFilterResource{
+			Label:      fmt.Sprintf("%s_legacy_browsers", project),
+			FilterID:   "legacy-browsers",
+			Active:     len(filters.LegacyBrowsers) > 0,
+			Subfilters: filters.LegacyBrowsers,
+		})
+	}
+	return result
+}
+
+type issueAlertResource struct {
+	IssueAlert
+	Label string
+
+	// ConditionsJSON, FiltersJSON and ActionsJSON hold the JSON encoded rules
+	// for providers which expect them as a string.
+	ConditionsJSON string
+	FiltersJSON    string
+	ActionsJSON    string
+}
+
+// issueAlertResources derives a unique resource label for each alert of a
+// project. The label is based on the alert name so reordering alerts does not
+// recreate them.
+func issueAlertResources(project string, alerts []IssueAlert) ([]issueAlertResource, error) {
+	result := make([]issueAlertResource, 0, len(alerts))
+	seen := map[string]bool{}
+	for _, alert := range alerts {
+		label, err := alertLabel(project, alert.Name, seen)
+		if err != nil {
+			return nil, err
+		}
+		resource := issueAlertResource{IssueAlert: alert, Label: label}
+		if resource.ConditionsJSON, err = encodeRules(alert.Conditions); err != nil {
+			return nil, err
+		}
+		if resource.FiltersJSON, err = encodeRules(alert.Filters); err != nil {
+			return nil, err
+		}
+		if resource.ActionsJSON, err = encodeRules(alert.Actions); err != nil {
+			return nil, err
+		}
+		result = append(result, resource)
+	}
+	return result, nil
+}
+
+type metricAlertResource struct {
+	MetricAlert
+	Label string
+}
+
+// metricAlertResources derives the resource labels for the metric alerts of a
+// project and fills in the defaults of the triggers.
+func metricAlertResources(project string, alerts []MetricAlert) ([]metricAlertResource, error) {
+	result := make([]metricAlertResource, 0, len(alerts))
+	seen := map[string]bool{}
+	for _, alert := range alerts {
+		label, err := alertLabel(project, alert.Name, seen)
+		if err != nil {
+			return nil, err
+		}
+		if alert.Dataset == "" {
+			alert.Dataset = "events"
+		}
+
+		triggers := make([]MetricAlertTrigger, 0, len(alert.Triggers))
+		for _, trigger := range alert.Triggers {
+			if trigger.ThresholdType == nil {
+				trigger.ThresholdType = intPtr(alert.ThresholdType)
+			}
+			triggers = append(triggers, trigger)
+		}
+		alert.Triggers = triggers
+
+		result = append(result, metricAlertResource{MetricAlert: alert, Label: label})
+	}
+	return result, nil
+}
+
+func encodeRules(rules []map[string]any) (string, error) {
+	if rules == nil {
+		return "", nil
+	}
+	b, err := json.Marshal(rules)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func alertLabel(component, name string, seen map[string]bool) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("alert name is required")
+	}
+	label := fmt.Sprintf("%s_%s", component, helpers.Slugify(name))
+	if seen[label] {
+		return "", fmt.Errorf("duplicate alert %s", name)
+	}
+	seen[label] = true
+	return label, nil
 }
@@ -1,6 +1,8 @@
 package internal
 
 import (
+	"strings"
+
 	"github.com/mach-composer/mach-composer-plugin-sdk/v2/schema"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -34,6 +36,8 @@ func TestSetGlobalConfigFull(t *testing.T) {
 			RateLimitCount:   intPtr(10),
 			TrackDeployments: boolPtr(true),
 			ExposeKey:        boolPtr(false),
+			ExposeRelease:    boolPtr(false),
+			CreateProject:    boolPtr(false),
 			Project:          "test",
 		},
 		AuthToken:    "foobar",
@@ -64,6 +68,8 @@ func TestSetGlobalConfigDefaults(t *testing.T) {
 			RateLimitCount:   nil,
 			TrackDeployments: boolPtr(true),
 			ExposeKey:        boolPtr(false),
+			ExposeRelease:    boolPtr(false),
+			CreateProject:    boolPtr(false),
 		},
 	}, p.globalConfig)
 }
@@ -82,6 +88,8 @@ func TestSetGlobalConfigWithFalse(t *testing.T) {
 			RateLimitCount:   nil,
 			TrackDeployments: boolPtr(false),
 			ExposeKey:        boolPtr(false),
+			ExposeRelease:    boolPtr(false),
+			CreateProject:    boolPtr(false),
 		},
 	}, p.globalConfig)
 }
@@ -109,6 +117,23 @@ func TestSetSiteConfigFull(t *testing.T) {
 	}, p.siteConfigs["my-site"])
 }
 
+func TestSetSiteConfigProvider(t *testing.T) {
+	p := NewSentryPlugin()
+
+	err := p.SetSiteConfig("my-site", map[string]any{
+		"auth_token":   "foobar",
+		"base_url":     "https://sentry.example.com/api/",
+		"organization": "my-org",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, SiteConfig{
+		AuthToken:    "foobar",
+		BaseURL:      "https://sentry.example.com/api/",
+		Organization: "my-org",
+		Components:   map[string]SiteComponentConfig{},
+	}, p.siteConfigs["my-site"])
+}
+
 func TestSetSiteConfigInvalid(t *testing.T) {
 	p := NewSentryPlugin()
 
@@ -218,6 +243,7 @@ func TestRenderTerraformComponentWithAuthToken(t *testing.T) {
 	p.SetGlobalConfig(map[string]any{
 		"auth_token":   "foobar",
 		"organization": "my-org",
+		"project":      "my-project",
 	})
 	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{
 		"dsn": "https://sentry.io/123",
@@ -236,6 +262,7 @@ func TestRenderTerraformComponentWithExposeKeyGlobal(t *testing.T) {
 	p.SetGlobalConfig(map[string]any{
 		"auth_token":   "foobar",
 		"organization": "my-org",
+		"project":      "my-project",
 		"expose_key":   true,
 	})
 	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{
@@ -255,6 +282,7 @@ func TestRenderTerraformComponentWithExposeKeySiteOverride(t *testing.T) {
 	p.SetGlobalConfig(map[string]any{
 		"auth_token":   "foobar",
 		"organization": "my-org",
+		"project":      "my-project",
 		"expose_key":   true,
 	})
 	p.SetSiteConfig("my-site", map[string]any{
@@ -277,6 +305,7 @@ func TestRenderTerraformComponentWithExposeKeyComponentOverride(t *testing.T) {
 	p.SetGlobalConfig(map[string]any{
 		"auth_token":   "foobar",
 		"organization": "my-org",
+		"project":      "my-project",
 	})
 	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{
 		"dsn":        "https://sentry.io/123",
@@ -290,14 +319,1819 @@ func TestRenderTerraformComponentWithExposeKeyComponentOverride(t *testing.T) {
 	assert.Contains(t, result.Variables, "sentry_key = sentry_key.my-component.secret")
 }
 
-func TestSetComponentConfig(t *testing.T) {
+func TestRenderTerraformComponentWithCreateProject(t *testing.T) {
 	p := NewSentryPlugin()
 
-	err := p.SetComponentConfig("my-component", "abc123", map[string]any{})
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":     "foobar",
+		"organization":   "my-org",
+		"create_project": true,
+		"team":           "my-team",
+		"platform":       "javascript",
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+		"project_name": "My Component",
+	})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
 	assert.NoError(t, err)
-	assert.Equal(t, 1, len(p.componentConfigs))
+	assert.Contains(t, result.Resources, `resource "sentry_project" "my-component"`)
+	assert.Contains(t, result.Resources, `teams             = ["my-team"]`)
+	assert.Contains(t, result.Resources, `name              = "My Component"`)
+	assert.Contains(t, result.Resources, `slug              = "my-component"`)
+	assert.Contains(t, result.Resources, `platform = "javascript"`)
+	assert.Contains(t, result.Resources, `project           = sentry_project.my-component.slug`)
+	assert.Contains(t, result.Resources, `projects        = [sentry_project.my-component.slug]`)
+}
 
-	assert.Equal(t, ComponentConfig{
-		Version: "abc123",
-	}, p.componentConfigs["my-component"])
+func TestRenderTerraformComponentWithCreateProjectWithoutTeam(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":     "foobar",
+		"organization":   "my-org",
+		"create_project": true,
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	_, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.ErrorContains(t, err, "create_project requires a team")
+}
+
+func TestRenderTerraformComponentWithoutCreateProject(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":   "foobar",
+		"organization": "my-org",
+		"project":      "my-project",
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.NotContains(t, result.Resources, `resource "sentry_project"`)
+	assert.Contains(t, result.Resources, `project           = "my-project"`)
+}
+
+func TestRenderTerraformComponentWithAlerts(t *testing.T) {
+	p := NewSentryPlugin()
+
+	err := p.SetGlobalConfig(map[string]any{
+		"auth_token":   "foobar",
+		"organization": "my-org",
+		"project":      "my-project",
+		"alerts": []any{
+			map[string]any{
+				"name": "Global alert",
+			},
+		},
+	})
+	assert.NoError(t, err)
+	err = p.SetSiteConfig("my-site", map[string]any{
+		"alerts": []any{
+			map[string]any{
+				"name":         "New issue",
+				"action_match": "all",
+				"frequency":    60,
+				"environment":  "production",
+				"conditions": []any{
+					map[string]any{
+						"id": "sentry.rules.conditions.first_seen_event.FirstSeenEventCondition",
+					},
+				},
+				"actions": []any{
+					map[string]any{
+						"id":               "sentry.mail.actions.NotifyEmailAction",
+						"targetType":       "IssueOwners",
+						"targetIdentifier": "",
+					},
+				},
+			},
+		},
+	})
+	assert.NoError(t, err)
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.NotContains(t, result.Resources, "Global alert")
+	assert.Contains(t, result.Resources, `resource "sentry_issue_alert" "my-project_new_issue"`)
+	assert.Contains(t, result.Resources, `project           = "my-project"`)
+	assert.Contains(t, result.Resources, `action_match      = "all"`)
+	assert.Contains(t, result.Resources, `filter_match      = "any"`)
+	assert.Contains(t, result.Resources, `frequency         = 60`)
+	assert.Contains(t, result.Resources, `environment = "production"`)
+	assert.Contains(t, result.Resources, `id = "sentry.rules.conditions.first_seen_event.FirstSeenEventCondition"`)
+	assert.Contains(t, result.Resources, `targetType       = "IssueOwners"`)
+	assert.NotContains(t, result.Resources, "filters")
+}
+
+func TestRenderTerraformComponentWithDuplicateAlerts(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":   "foobar",
+		"organization": "my-org",
+		"project":      "my-project",
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+		"alerts": []any{
+			map[string]any{"name": "New issue"},
+			map[string]any{"name": "new-issue"},
+		},
+	})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	_, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.ErrorContains(t, err, "duplicate alert new-issue")
+}
+
+func TestSetSiteComponentConfigInvalidAlert(t *testing.T) {
+	p := NewSentryPlugin()
+
+	err := p.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+		"alerts": []any{
+			map[string]any{"action_match": "sometimes"},
+		},
+	})
+	assert.Error(t, err)
+}
+
+func TestRenderTerraformComponentWithMetricAlerts(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":   "foobar",
+		"organization": "my-org",
+		"project":      "my-project",
+		"metric_alerts": []any{
+			map[string]any{
+				"name":        "Error rate",
+				"aggregate":   "count()",
+				"query":       "event.type:error",
+				"time_window": 60,
+				"environment": "production",
+				"triggers": []any{
+					map[string]any{
+						"label":           "critical",
+						"alert_threshold": 300,
+						"actions": []any{
+							map[string]any{
+								"type":              "email",
+								"target_type":       "team",
+								"target_identifier": "123",
+							},
+						},
+					},
+					map[string]any{
+						"label":           "warning",
+						"alert_threshold": 0.5,
+						"threshold_type":  1,
+					},
+				},
+			},
+		},
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Resources, `resource "sentry_metric_alert" "my-project_error_rate"`)
+	assert.Contains(t, result.Resources, `dataset           = "events"`)
+	assert.Contains(t, result.Resources, `aggregate         = "count()"`)
+	assert.Contains(t, result.Resources, `query             = "event.type:error"`)
+	assert.Contains(t, result.Resources, `time_window       = 60`)
+	assert.Contains(t, result.Resources, `alert_threshold = 300`)
+	assert.Contains(t, result.Resources, `alert_threshold = 0.5`)
+	assert.Contains(t, result.Resources, `threshold_type  = 0`)
+	assert.Contains(t, result.Resources, `threshold_type  = 1`)
+	assert.Contains(t, result.Resources, `target_identifier = "123"`)
+	assert.NotContains(t, result.Resources, "resolve_threshold")
+}
+
+func TestSetGlobalConfigInvalidMetricAlert(t *testing.T) {
+	p := NewSentryPlugin()
+
+	err := p.SetGlobalConfig(map[string]any{
+		"metric_alerts": []any{
+			map[string]any{
+				"name":      "Error rate",
+				"aggregate": "count()",
+			},
+		},
+	})
+	assert.Error(t, err)
+}
+
+func TestRenderTerraformComponentManagedModeWithoutAuthToken(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"mode":         "managed",
+		"organization": "my-org",
+		"project":      "my-project",
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Equal(t, "sentry_dsn = sentry_key.my-component.dsn_secret", result.Variables)
+	assert.Contains(t, result.Resources, `resource "sentry_key" "my-component"`)
+
+	providers, err := p.RenderTerraformProviders("my-site")
+	assert.NoError(t, err)
+	assert.Contains(t, providers, `source = "labd/sentry"`)
+
+	resources, err := p.RenderTerraformResources("my-site")
+	assert.NoError(t, err)
+	assert.Contains(t, resources, `provider "sentry"`)
+	assert.NotContains(t, resources, "token")
+}
+
+func TestRenderTerraformComponentUnmanagedSiteOverride(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":   "foobar",
+		"organization": "my-org",
+		"project":      "my-project",
+	})
+	p.SetSiteConfig("my-site", map[string]any{
+		"mode": "unmanaged",
+		"dsn":  "https://sentry.io/123",
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Equal(t, `sentry_dsn = "https://sentry.io/123"`, result.Variables)
+	assert.Empty(t, result.Resources)
+
+	providers, err := p.RenderTerraformProviders("my-site")
+	assert.NoError(t, err)
+	assert.Empty(t, providers)
+
+	providers, err = p.RenderTerraformProviders("other-site")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, providers)
+}
+
+func TestRenderTerraformComponentManagedComponentInUnmanagedSite(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"organization": "my-org",
+		"project":      "my-project",
+		"dsn":          "https://sentry.io/123",
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+		"mode": "managed",
+	})
+
+	providers, err := p.RenderTerraformProviders("my-site")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, providers)
+}
+
+func TestRenderTerraformComponentModeMissingFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]any
+		err    string
+	}{
+		{
+			name:   "managed without organization",
+			config: map[string]any{"mode": "managed", "project": "my-project"},
+			err:    "mode managed requires organization to be set",
+		},
+		{
+			name:   "managed without project",
+			config: map[string]any{"mode": "managed", "organization": "my-org"},
+			err:    "mode managed requires project to be set",
+		},
+		{
+			name:   "lookup without project",
+			config: map[string]any{"mode": "lookup", "organization": "my-org"},
+			err:    "mode lookup requires project to be set",
+		},
+		{
+			name:   "unmanaged without dsn",
+			config: map[string]any{"mode": "unmanaged", "auth_token": "foobar"},
+			err:    "mode unmanaged requires dsn to be set",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := NewSentryPlugin()
+			assert.NoError(t, p.SetGlobalConfig(tc.config))
+			p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+			_, err := p.RenderTerraformComponent("my-site", "my-component")
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+func TestRenderTerraformComponentLookupMode(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"mode":         "lookup",
+		"auth_token":   "foobar",
+		"organization": "my-org",
+		"project":      "my-project",
+		"expose_key":   true,
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+		"key_name": "production",
+	})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Variables, "sentry_dsn = data.sentry_key.my-component.dsn_secret")
+	assert.Contains(t, result.Variables, "sentry_key = data.sentry_key.my-component.secret")
+	assert.Contains(t, result.Resources, `data "sentry_key" "my-component"`)
+	assert.Contains(t, result.Resources, `name = "production"`)
+	assert.NotContains(t, result.Resources, "first")
+	assert.NotContains(t, result.Resources, `resource "sentry_key"`)
+	assert.NotContains(t, result.Resources, "sentry_release_deployment")
+
+	providers, err := p.RenderTerraformProviders("my-site")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, providers)
+}
+
+func TestRenderTerraformComponentLookupModeByID(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"organization": "my-org",
+		"project":      "my-project",
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+		"mode":   "lookup",
+		"key_id": "abcdef",
+	})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Equal(t, "sentry_dsn = data.sentry_key.my-component.dsn_secret", result.Variables)
+	assert.Contains(t, result.Resources, `id = "abcdef"`)
+	assert.NotContains(t, result.Resources, "name")
+	assert.NotContains(t, result.Resources, "first")
+}
+
+func TestRenderTerraformComponentLookupModeWithoutKey(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"organization": "my-org",
+		"project":      "my-project",
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+		"mode": "lookup",
+	})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	_, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.ErrorContains(t, err, "mode lookup requires key_name or key_id to be set")
+}
+
+func TestSetGlobalConfigInvalidMode(t *testing.T) {
+	p := NewSentryPlugin()
+
+	err := p.SetGlobalConfig(map[string]any{
+		"mode": "sometimes",
+	})
+	assert.Error(t, err)
+}
+
+func TestRenderTerraformSiteProviderOverride(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":   "global-token",
+		"organization": "global-org",
+		"project":      "my-project",
+		"alerts": []any{
+			map[string]any{"name": "New issue"},
+		},
+	})
+	p.SetSiteConfig("eu-1", map[string]any{
+		"organization": "eu-org",
+		"base_url":     "https://eu.sentry.io/api/",
+	})
+	p.SetSiteComponentConfig("eu-1", "my-component", map[string]any{})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	resources, err := p.RenderTerraformResources("eu-1")
+	assert.NoError(t, err)
+	assert.Contains(t, resources, `alias = "eu-1"`)
+	assert.Contains(t, resources, `token = "global-token"`)
+	assert.Contains(t, resources, `base_url = "https://eu.sentry.io/api/"`)
+
+	result, err := p.RenderTerraformComponent("eu-1", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Resources, `organization      = "eu-org"`)
+	assert.NotContains(t, result.Resources, "global-org")
+	assert.Equal(t, 3, strings.Count(result.Resources, "provider          = sentry.eu-1"))
+
+	resources, err = p.RenderTerraformResources("us-1")
+	assert.NoError(t, err)
+	assert.NotContains(t, resources, "alias")
+
+	result, err = p.RenderTerraformComponent("us-1", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Resources, `organization      = "global-org"`)
+	assert.NotContains(t, result.Resources, "provider ")
+}
+
+func TestRenderTerraformSiteProviderAliasSanitized(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":   "global-token",
+		"organization": "global-org",
+		"project":      "my-project",
+	})
+	p.SetSiteConfig("1-eu", map[string]any{
+		"organization": "eu-org",
+	})
+	p.SetSiteComponentConfig("1-eu", "my-component", map[string]any{})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	resources, err := p.RenderTerraformResources("1-eu")
+	assert.NoError(t, err)
+	assert.Contains(t, resources, `alias = "_1-eu"`)
+
+	result, err := p.RenderTerraformComponent("1-eu", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Resources, "provider          = sentry._1-eu")
+}
+
+func TestRenderTerraformSiteAuthTokenEnablesManagedMode(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"organization": "my-org",
+		"project":      "my-project",
+	})
+	p.SetSiteConfig("my-site", map[string]any{
+		"auth_token": "site-token",
+	})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Equal(t, "sentry_dsn = sentry_key.my-component.dsn_secret", result.Variables)
+	assert.Contains(t, result.Resources, "provider          = sentry.my-site")
+}
+
+func TestRenderTerraformProvidersDefaultVersion(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token": "foobar",
+	})
+
+	result, err := p.RenderTerraformProviders("my-site")
+	assert.NoError(t, err)
+	assert.Contains(t, result, `source = "labd/sentry"`)
+	assert.Contains(t, result, `version = "~> 1.0.2"`)
+
+	p.Configure("test", ">=1.1.0")
+	result, err = p.RenderTerraformProviders("my-site")
+	assert.NoError(t, err)
+	assert.Contains(t, result, `version = ">= 1.1.0"`)
+}
+
+func TestRenderTerraformJianyuanProvider(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":      "foobar",
+		"organization":    "my-org",
+		"project":         "my-project",
+		"provider_source": "jianyuan/sentry",
+		"expose_key":      true,
+		"alerts": []any{
+			map[string]any{
+				"name": "New issue",
+				"conditions": []any{
+					map[string]any{"id": "sentry.rules.conditions.first_seen_event.FirstSeenEventCondition"},
+				},
+			},
+		},
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	providers, err := p.RenderTerraformProviders("my-site")
+	assert.NoError(t, err)
+	assert.Contains(t, providers, `source = "jianyuan/sentry"`)
+	assert.Contains(t, providers, `version = "~> 0.14.5"`)
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Variables, `sentry_dsn = sentry_key.my-component.dsn["secret"]`)
+	assert.Contains(t, result.Variables, `sentry_key = sentry_key.my-component.secret`)
+	assert.Contains(t, result.Resources, `resource "sentry_key" "my-component"`)
+	assert.NotContains(t, result.Resources, "sentry_release_deployment")
+	assert.Contains(t, result.Resources, `conditions = "[{\"id\":\"sentry.rules.conditions.first_seen_event.FirstSeenEventCondition\"}]"`)
+	assert.NotContains(t, result.Resources, "actions")
+}
+
+func TestSetGlobalConfigInvalidProviderSource(t *testing.T) {
+	p := NewSentryPlugin()
+
+	err := p.SetGlobalConfig(map[string]any{
+		"provider_source": "hashicorp/sentry",
+	})
+	assert.Error(t, err)
+}
+
+func TestRenderTerraformTeams(t *testing.T) {
+	p := NewSentryPlugin()
+
+	err := p.SetGlobalConfig(map[string]any{
+		"auth_token":     "foobar",
+		"organization":   "my-org",
+		"create_project": true,
+		"team":           "backend",
+		"teams": []any{
+			map[string]any{"slug": "backend", "name": "Backend"},
+			map[string]any{"slug": "frontend", "site": "my-site"},
+		},
+	})
+	assert.NoError(t, err)
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{})
+	p.SetSiteComponentConfig("my-site", "my-frontend", map[string]any{
+		"team": "frontend",
+	})
+	p.SetSiteComponentConfig("other-site", "my-frontend", map[string]any{
+		"team": "frontend",
+	})
+	p.SetSiteComponentConfig("other-site", "my-mobile", map[string]any{
+		"team": "mobile",
+	})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+	p.SetComponentConfig("my-frontend", "abc123", map[string]any{})
+	p.SetComponentConfig("my-mobile", "abc123", map[string]any{})
+
+	resources, err := p.RenderTerraformResources("my-site")
+	assert.NoError(t, err)
+	assert.Contains(t, resources, `resource "sentry_team" "team_backend"`)
+	assert.Contains(t, resources, `name              = "Backend"`)
+	assert.Contains(t, resources, `resource "sentry_team" "team_frontend"`)
+	assert.Contains(t, resources, `name              = "frontend"`)
+
+	resources, err = p.RenderTerraformResources("other-site")
+	assert.NoError(t, err)
+	assert.Contains(t, resources, `resource "sentry_team" "team_backend"`)
+	assert.Contains(t, resources, `data "sentry_team" "team_frontend"`)
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Resources, `teams             = [sentry_team.team_backend.slug]`)
+
+	result, err = p.RenderTerraformComponent("my-site", "my-frontend")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Resources, `teams             = [sentry_team.team_frontend.slug]`)
+
+	result, err = p.RenderTerraformComponent("other-site", "my-frontend")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Resources, `teams             = [data.sentry_team.team_frontend.slug]`)
+
+	result, err = p.RenderTerraformComponent("other-site", "my-mobile")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Resources, `teams             = ["mobile"]`)
+}
+
+func TestRenderTerraformTeamsLookupMode(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"mode":         "lookup",
+		"organization": "my-org",
+		"project":      "my-project",
+		"teams": []any{
+			map[string]any{"slug": "backend"},
+		},
+	})
+
+	resources, err := p.RenderTerraformResources("my-site")
+	assert.NoError(t, err)
+	assert.NotContains(t, resources, "sentry_team")
+}
+
+func TestRenderTerraformComponentWithInboundFilters(t *testing.T) {
+	p := NewSentryPlugin()
+
+	err := p.SetGlobalConfig(map[string]any{
+		"auth_token":     "foobar",
+		"organization":   "my-org",
+		"project":        "my-project",
+		"create_project": true,
+		"team":           "my-team",
+		"inbound_filters": map[string]any{
+			"browser_extensions": true,
+			"legacy_browsers":    []any{"ie_pre_9", "safari_pre_6"},
+			"error_messages":     []any{"ResizeObserver loop limit exceeded"},
+		},
+	})
+	assert.NoError(t, err)
+	p.SetSiteConfig("my-site", map[string]any{
+		"inbound_filters": map[string]any{
+			"localhost": false,
+		},
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Resources, `resource "sentry_project_inbound_data_filter" "my-project_browser_extensions"`)
+	assert.Contains(t, result.Resources, `filter_id         = "browser-extensions"`)
+	assert.Contains(t, result.Resources, `resource "sentry_project_inbound_data_filter" "my-project_localhost"`)
+	assert.Contains(t, result.Resources, `active            = false`)
+	assert.Contains(t, result.Resources, `subfilters = ["ie_pre_9", "safari_pre_6"]`)
+	assert.NotContains(t, result.Resources, "web-crawlers")
+	assert.Contains(t, result.Resources, `error_messages = ["ResizeObserver loop limit exceeded"]`)
+	assert.NotContains(t, result.Resources, "releases")
+}
+
+func TestRenderTerraformComponentWithProjectFiltersWithoutCreateProject(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":   "foobar",
+		"organization": "my-org",
+		"project":      "my-project",
+		"inbound_filters": map[string]any{
+			"releases": []any{"1.0.*"},
+		},
+	})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.NotContains(t, result.Resources, "sentry_project_inbound_data_filter")
+	assert.NotContains(t, result.Resources, "1.0.*")
+}
+
+func TestRenderTerraformComponentWithSpikeProtection(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":       "foobar",
+		"organization":     "my-org",
+		"project":          "my-project",
+		"create_project":   true,
+		"team":             "my-team",
+		"spike_protection": true,
+	})
+	p.SetSiteConfig("my-site", map[string]any{
+		"spike_protection": false,
+	})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Resources, `resource "sentry_project_spike_protection" "my-project"`)
+	assert.Contains(t, result.Resources, `enabled           = false`)
+
+	result, err = p.RenderTerraformComponent("other-site", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Resources, `enabled           = true`)
+}
+
+func TestRenderTerraformComponentWithSpikeProtectionWithoutCreateProject(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":       "foobar",
+		"organization":     "my-org",
+		"project":          "my-project",
+		"spike_protection": true,
+	})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+	p.SetComponentConfig("other-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Resources, `resource "sentry_project_spike_protection" "my-project" {`)
+	assert.Contains(t, result.Resources, `project           = "my-project"`)
+
+	// The existing project is shared, so its spike protection is rendered once.
+	result, err = p.RenderTerraformComponent("my-site", "other-component")
+	assert.NoError(t, err)
+	assert.NotContains(t, result.Resources, "sentry_project_spike_protection")
+}
+
+func TestRenderTerraformComponentWithoutSpikeProtection(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":   "foobar",
+		"organization": "my-org",
+		"project":      "my-project",
+	})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.NotContains(t, result.Resources, "sentry_project_spike_protection")
+}
+
+func TestRenderTerraformComponentWithExposeRelease(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.Configure("production", "")
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":     "foobar",
+		"organization":   "my-org",
+		"project":        "my-project",
+		"expose_release": true,
+	})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Variables, `sentry_environment = "production"`)
+	assert.Contains(t, result.Variables, `sentry_release = "abc123"`)
+	assert.Contains(t, result.Resources, `version         = "abc123"`)
+	assert.Contains(t, result.Resources, `environment     = "production"`)
+}
+
+func TestRenderTerraformComponentWithExposeReleaseUnmanaged(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.Configure("production", "")
+	p.SetGlobalConfig(map[string]any{})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+		"dsn":            "https://sentry.io/123",
+		"expose_release": true,
+	})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Equal(t, `sentry_dsn = "https://sentry.io/123"
+sentry_environment = "production"
+sentry_release = "abc123"`, result.Variables)
+}
+
+func TestRenderTerraformComponentWithReleaseNameTemplate(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.Configure("production", "")
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":            "foobar",
+		"organization":          "my-org",
+		"project":               "my-project",
+		"expose_release":        true,
+		"release_name_template": "{{ .Component }}@{{ .Version }}",
+	})
+	p.SetSiteConfig("my-site", map[string]any{
+		"release_name_template": "{{ .Site }}-{{ .Environment }}-{{ .Component }}@{{ .Version }}",
+	})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("other-site", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Variables, `sentry_release = "my-component@abc123"`)
+	assert.Contains(t, result.Resources, `version         = "my-component@abc123"`)
+
+	result, err = p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Variables, `sentry_release = "my-site-production-my-component@abc123"`)
+	assert.Contains(t, result.Resources, `version         = "my-site-production-my-component@abc123"`)
+}
+
+func TestRenderTerraformComponentWithInvalidReleaseNameTemplate(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"release_name_template": "{{ .Component",
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+		"dsn": "https://sentry.io/123",
+	})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	_, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.ErrorContains(t, err, "invalid release_name_template for component my-component in site my-site")
+}
+
+func TestRenderTerraformComponentWithEnvironmentMapping(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.Configure("production", "")
+	err := p.SetGlobalConfig(map[string]any{
+		"auth_token":     "foobar",
+		"organization":   "my-org",
+		"project":        "my-project",
+		"expose_release": true,
+		"environment_mapping": map[string]any{
+			"production": "prod",
+		},
+	})
+	assert.NoError(t, err)
+	err = p.SetSiteConfig("eu-1", map[string]any{
+		"environment_mapping": map[string]any{
+			"production": "prod-eu",
+		},
+	})
+	assert.NoError(t, err)
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("us-1", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Variables, `sentry_environment = "prod"`)
+	assert.Contains(t, result.Resources, `environment     = "prod"`)
+	assert.Contains(t, result.Resources, `name              = "prod-us-1-my-component"`)
+
+	result, err = p.RenderTerraformComponent("eu-1", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Variables, `sentry_environment = "prod-eu"`)
+	assert.Contains(t, result.Resources, `environment     = "prod-eu"`)
+	assert.Contains(t, result.Resources, `name              = "prod-eu-eu-1-my-component"`)
+
+	p.Configure("test", "")
+	result, err = p.RenderTerraformComponent("us-1", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Variables, `sentry_environment = "test"`)
+
+	// The site mapping only applies to the mapped environments.
+	result, err = p.RenderTerraformComponent("eu-1", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Variables, `sentry_environment = "test"`)
+}
+
+func TestRenderTerraformComponentWithSharedKey(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.Configure("production", "")
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":     "foobar",
+		"organization":   "my-org",
+		"project":        "my-project",
+		"key_scope":      "environment",
+		"key_owner_site": "my-site",
+	})
+	p.SetComponentConfig("first-component", "abc123", map[string]any{})
+	p.SetComponentConfig("second-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "first-component")
+	assert.NoError(t, err)
+	assert.Equal(t, "sentry_dsn = sentry_key.shared_my_project.dsn_secret", result.Variables)
+	assert.Contains(t, result.Resources, `resource "sentry_key" "shared_my_project"`)
+	assert.Contains(t, result.Resources, `name              = "production"`)
+	assert.Contains(t, result.Resources, "from              = sentry_key.first-component\nto                = sentry_key.shared_my_project\n")
+
+	result, err = p.RenderTerraformComponent("my-site", "second-component")
+	assert.NoError(t, err)
+	assert.Equal(t, "sentry_dsn = sentry_key.shared_my_project.dsn_secret", result.Variables)
+	assert.NotContains(t, result.Resources, "sentry_key")
+
+	result, err = p.RenderTerraformComponent("other-site", "first-component")
+	assert.NoError(t, err)
+	assert.Equal(t, "sentry_dsn = data.sentry_key.shared_my_project.dsn_secret", result.Variables)
+	assert.Contains(t, result.Resources, `data "sentry_key" "shared_my_project"`)
+	assert.NotContains(t, result.Resources, `resource "sentry_key"`)
+}
+
+func TestRenderTerraformComponentWithKeyNameTemplate(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.Configure("production", "")
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":        "foobar",
+		"organization":      "my-org",
+		"project":           "my-project",
+		"key_name_template": "{{ .Component }} ({{ .Site }})",
+	})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Resources, `name              = "my-component (my-site)"`)
+}
+
+func TestRenderTerraformComponentWithKeyRotation(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.Configure("production", "")
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":        "foobar",
+		"organization":      "my-org",
+		"project":           "my-project",
+		"rate_limit_window": 60,
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+		"key_rotation": map[string]any{
+			"generation": 1,
+		},
+	})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Equal(t, "sentry_dsn = sentry_key.my-component_v1.dsn_secret", result.Variables)
+	assert.Contains(t, result.Resources, `resource "sentry_key" "my-component"`)
+	assert.Contains(t, result.Resources, `name              = "production-my-site-my-component"`)
+	assert.Contains(t, result.Resources, `resource "sentry_key" "my-component_v1"`)
+	assert.Contains(t, result.Resources, `name              = "production-my-site-my-component-v1"`)
+	assert.Equal(t, 2, strings.Count(result.Resources, "rate_limit_window = 60"))
+}
+
+func TestRenderTerraformComponentWithKeyRotationNotMoved(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":   "foobar",
+		"organization": "my-org",
+		"project":      "my-project",
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+		"key_rotation": map[string]any{
+			"generation":       1,
+			"keep_generations": 0,
+		},
+	})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Resources, `resource "sentry_key" "my-component_v1"`)
+	assert.NotContains(t, result.Resources, "moved {")
+}
+
+func TestRenderTerraformComponentWithSharedKeyRotation(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":     "foobar",
+		"organization":   "my-org",
+		"project":        "my-project",
+		"key_scope":      "environment",
+		"key_owner_site": "my-site",
+		"key_rotation": map[string]any{
+			"generation": 1,
+		},
+	})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Resources, `resource "sentry_key" "shared_my_project_v1"`)
+	assert.Contains(t, result.Resources, "from              = sentry_key.my-component\nto                = sentry_key.shared_my_project\n")
+	assert.Equal(t, 1, strings.Count(result.Resources, "moved {"))
+}
+
+func TestRenderTerraformComponentWithKeyImport(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":     "foobar",
+		"organization":   "my-org",
+		"create_project": true,
+		"team":           "my-team",
+	})
+	err := p.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+		"project": "my-project",
+		"key_id":  "abcdef",
+		"key_rotation": map[string]any{
+			"generation": 1,
+		},
+	})
+	assert.NoError(t, err)
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Resources, `to                = sentry_key.my-component
+id                = "my-org/my-project/abcdef"`)
+	assert.Contains(t, result.Resources, `to                = sentry_project.my-project
+id                = "my-org/my-project"`)
+}
+
+func TestRenderTerraformComponentWithoutKeyImport(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":   "foobar",
+		"organization": "my-org",
+		"project":      "my-project",
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.NotContains(t, result.Resources, "import {")
+}
+
+func TestRenderTerraformComponentWithKeyImportExistingProject(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":   "foobar",
+		"organization": "my-org",
+		"project":      "my-project",
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+		"key_id": "abcdef",
+	})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(result.Resources, "import {"))
+	assert.Contains(t, result.Resources, `id                = "my-org/my-project/abcdef"`)
+}
+
+func TestRenderTerraformComponentWithKeyImportRotation(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":   "foobar",
+		"organization": "my-org",
+		"project":      "my-project",
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+		"key_id": "abcdef",
+		"key_rotation": map[string]any{
+			"generation":       2,
+			"keep_generations": 2,
+		},
+	})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	// The existing key is the original key, not the oldest kept generation.
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Resources, `to                = sentry_key.my-component
+id                = "my-org/my-project/abcdef"`)
+
+	// Once the original key is rotated out it is no longer imported, so the
+	// revoked key is not adopted as a rotated key.
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+		"key_id": "abcdef",
+		"key_rotation": map[string]any{
+			"generation":       2,
+			"keep_generations": 0,
+		},
+	})
+
+	result, err = p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Resources, `resource "sentry_key" "my-component_v2" {`)
+	assert.NotContains(t, result.Resources, "import {")
+
+	doc, err := p.RenderTerraformJSON("my-site", []string{"my-component"})
+	assert.NoError(t, err)
+	assert.NotContains(t, string(doc), `"import"`)
+}
+
+func TestSetComponentConfig(t *testing.T) {
+	p := NewSentryPlugin()
+
+	err := p.SetComponentConfig("my-component", "abc123", map[string]any{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(p.componentConfigs))
+
+	assert.Equal(t, ComponentConfig{
+		Version: "abc123",
+	}, p.componentConfigs["my-component"])
+}
+
+func TestRenderTerraformComponentWithPreviousNames(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":     "foobar",
+		"organization":   "my-org",
+		"project":        "my-project",
+		"create_project": true,
+		"team":           "my-team",
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{})
+	err := p.SetComponentConfig("my-component", "abc123", map[string]any{
+		"previous_names": []any{"old-component"},
+	})
+	assert.NoError(t, err)
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	// The project is labeled after its slug, so it is not moved.
+	assert.NotContains(t, result.Resources, "sentry_project.old-component")
+	assert.Contains(t, result.Resources, `from              = sentry_key.old-component
+to                = sentry_key.my-component`)
+}
+
+func TestRenderTerraformComponentWithPreviousNamesMovesOnlyComponentResources(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":     "foobar",
+		"organization":   "my-org",
+		"project":        "api_gateway",
+		"create_project": true,
+		"team":           "my-team",
+	})
+	p.SetSiteComponentConfig("my-site", "api", map[string]any{
+		"spike_protection": true,
+		"inbound_filters":  map[string]any{"localhost": true},
+		"alerts": []any{
+			map[string]any{
+				"name":         "errors",
+				"action_match": "any",
+				"frequency":    30,
+			},
+		},
+	})
+	err := p.SetComponentConfig("api", "abc123", map[string]any{
+		"previous_names": []any{"old"},
+	})
+	assert.NoError(t, err)
+
+	result, err := p.RenderTerraformComponent("my-site", "api")
+	assert.NoError(t, err)
+	assert.NotContains(t, result.Resources, "sentry_project.old_gateway")
+	assert.NotContains(t, result.Resources, "sentry_project_spike_protection.old_gateway")
+	assert.NotContains(t, result.Resources, "sentry_project_inbound_data_filter.old_gateway")
+	assert.NotContains(t, result.Resources, "sentry_issue_alert.old_gateway")
+	assert.Contains(t, result.Resources, `from              = sentry_key.old
+to                = sentry_key.api`)
+}
+
+func TestRenderTerraformComponentWithPreviousNamesMovesDefaultProject(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":     "foobar",
+		"organization":   "my-org",
+		"create_project": true,
+		"team":           "my-team",
+	})
+	p.SetSiteComponentConfig("my-site", "api", map[string]any{
+		"spike_protection": true,
+	})
+	err := p.SetComponentConfig("api", "abc123", map[string]any{
+		"previous_names": []any{"old"},
+	})
+	assert.NoError(t, err)
+
+	result, err := p.RenderTerraformComponent("my-site", "api")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Resources, `from              = sentry_project.old
+to                = sentry_project.api`)
+	assert.Contains(t, result.Resources, `from              = sentry_project_spike_protection.old
+to                = sentry_project_spike_protection.api`)
+}
+
+func TestRenderTerraformComponentWithSharedKeyMove(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":     "foobar",
+		"organization":   "my-org",
+		"project":        "my-project",
+		"key_scope":      "environment",
+		"key_owner_site": "my-site",
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Resources, `from              = sentry_key.my-component
+to                = sentry_key.shared_my_project`)
+}
+
+func TestSetComponentConfigInvalid(t *testing.T) {
+	p := NewSentryPlugin()
+
+	err := p.SetComponentConfig("my-component", "abc123", map[string]any{
+		"previous_names": "old-component",
+	})
+	assert.Error(t, err)
+}
+
+func TestRenderTerraformComponentSanitizesLabels(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":     "foobar",
+		"organization":   "my-org",
+		"project":        "my-project",
+		"create_project": true,
+		"team":           "my-team",
+	})
+	p.SetSiteComponentConfig("my-site", "1st.component", map[string]any{
+		"alerts": []any{
+			map[string]any{
+				"name":       "Errors",
+				"conditions": []any{map[string]any{"id": "sentry.rules.conditions.first_seen_event.FirstSeenEventCondition"}},
+				"actions":    []any{map[string]any{"id": "sentry.mail.actions.NotifyEmailAction"}},
+			},
+		},
+	})
+	p.SetComponentConfig("1st.component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "1st.component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Variables, "sentry_dsn = sentry_key._1st_component.dsn_secret")
+	assert.Contains(t, result.Resources, `resource "sentry_project" "my-project" {`)
+	assert.Contains(t, result.Resources, `resource "sentry_key" "_1st_component" {`)
+	assert.Contains(t, result.Resources, `resource "sentry_issue_alert" "my-project_errors" {`)
+	assert.Contains(t, result.Resources, `name              = "-my-site-1st.component"`)
+	assert.Contains(t, result.Resources, "depends_on      = [ module._1st_component ]")
+}
+
+func TestRenderTerraformComponentLookupSanitizesLabel(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"mode":         "lookup",
+		"organization": "my-org",
+		"project":      "my-project",
+		"key_name":     "production",
+	})
+	p.SetComponentConfig("my.component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my.component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Variables, "sentry_dsn = data.sentry_key.my_component.dsn_secret")
+	assert.Contains(t, result.Resources, `data "sentry_key" "my_component" {`)
+}
+
+func TestSetComponentConfigIdentifierCollision(t *testing.T) {
+	p := NewSentryPlugin()
+
+	err := p.SetComponentConfig("my.component", "abc123", map[string]any{})
+	assert.NoError(t, err)
+
+	err = p.SetComponentConfig("my/component", "abc123", map[string]any{})
+	assert.EqualError(t, err, "components my.component and my/component map to the same Terraform identifier my_component")
+}
+
+func TestRenderTerraformComponentAddressCollision(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":   "foobar",
+		"organization": "my-org",
+	})
+	p.SetSiteComponentConfig("my-site", "shop", map[string]any{
+		"project": "shop",
+		"alerts": []any{
+			map[string]any{
+				"name":       "api errors",
+				"conditions": []any{map[string]any{"id": "sentry.rules.conditions.first_seen_event.FirstSeenEventCondition"}},
+				"actions":    []any{map[string]any{"id": "sentry.mail.actions.NotifyEmailAction"}},
+			},
+		},
+	})
+	for _, site := range []string{"my-site", "other-site"} {
+		p.SetSiteComponentConfig(site, "shop_api", map[string]any{
+			"project": "shop_api",
+			"alerts": []any{
+				map[string]any{
+					"name":       "errors",
+					"conditions": []any{map[string]any{"id": "sentry.rules.conditions.first_seen_event.FirstSeenEventCondition"}},
+					"actions":    []any{map[string]any{"id": "sentry.mail.actions.NotifyEmailAction"}},
+				},
+			},
+		})
+	}
+	p.SetComponentConfig("shop", "abc123", map[string]any{})
+	p.SetComponentConfig("shop_api", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "shop")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Resources, `resource "sentry_issue_alert" "shop_api_errors" {`)
+
+	// Rendering a component again does not collide with itself.
+	_, err = p.RenderTerraformComponent("my-site", "shop")
+	assert.NoError(t, err)
+
+	_, err = p.RenderTerraformComponent("my-site", "shop_api")
+	assert.EqualError(t, err, "components shop and shop_api both render sentry_issue_alert.shop_api_errors in site my-site")
+
+	// Sites are rendered separately, so the addresses only collide within a
+	// site.
+	_, err = p.RenderTerraformComponent("other-site", "shop_api")
+	assert.NoError(t, err)
+}
+
+func TestRenderTerraformComponentWithEnvironmentOverlay(t *testing.T) {
+	p := NewSentryPlugin()
+	p.Configure("production", "")
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":       "foobar",
+		"organization":     "my-org",
+		"project":          "my-project",
+		"rate_limit_count": 10,
+		"environments": map[string]any{
+			"production": map[string]any{
+				"rate_limit_window": 60,
+				"rate_limit_count":  1000,
+			},
+			"test": map[string]any{
+				"track_deployments": false,
+			},
+		},
+	})
+	p.SetSiteConfig("my-site", map[string]any{
+		"environments": map[string]any{
+			"production": map[string]any{
+				"project": "my-production-project",
+			},
+		},
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Resources, "rate_limit_count  = 1000")
+	assert.Contains(t, result.Resources, "rate_limit_window = 60")
+	assert.Contains(t, result.Resources, `project           = "my-production-project"`)
+	assert.Contains(t, result.Resources, "sentry_release_deployment")
+}
+
+func TestRenderTerraformComponentWithOtherEnvironmentOverlay(t *testing.T) {
+	p := NewSentryPlugin()
+	p.Configure("test", "")
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":       "foobar",
+		"organization":     "my-org",
+		"project":          "my-project",
+		"rate_limit_count": 10,
+		"environments": map[string]any{
+			"production": map[string]any{
+				"rate_limit_count": 1000,
+			},
+			"test": map[string]any{
+				"track_deployments": false,
+			},
+		},
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+		"rate_limit_window": 60,
+	})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Resources, "rate_limit_count  = 10")
+	assert.NotContains(t, result.Resources, "sentry_release_deployment")
+}
+
+func TestSetGlobalConfigInvalidEnvironment(t *testing.T) {
+	p := NewSentryPlugin()
+
+	err := p.SetGlobalConfig(map[string]any{
+		"environments": map[string]any{
+			"production": map[string]any{
+				"auth_token": "foobar",
+			},
+		},
+	})
+	assert.Error(t, err)
+}
+
+func TestRenderTerraformComponentDisabled(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":     "foobar",
+		"organization":   "my-org",
+		"project":        "my-project",
+		"expose_key":     true,
+		"key_scope":      "environment",
+		"key_owner_site": "my-site",
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+		"enabled": false,
+	})
+	p.SetSiteComponentConfig("my-site", "other-component", map[string]any{})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+	p.SetComponentConfig("other-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Equal(t, "sentry_dsn = \"\"\nsentry_key = \"\"", result.Variables)
+	assert.Empty(t, result.Resources)
+
+	// The shared key is rendered by the first enabled component.
+	result, err = p.RenderTerraformComponent("my-site", "other-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Resources, `resource "sentry_key" "shared_my_project" {`)
+}
+
+func TestRenderTerraformComponentDisabledWithoutVariables(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":   "foobar",
+		"organization": "my-org",
+		"project":      "my-project",
+	})
+	p.SetSiteConfig("my-site", map[string]any{
+		"enabled":            false,
+		"disabled_variables": "none",
+	})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Empty(t, result.Variables)
+	assert.Empty(t, result.Resources)
+}
+
+func TestRenderTerraformResourcesDisabledSite(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":   "foobar",
+		"organization": "my-org",
+		"project":      "my-project",
+	})
+	p.SetSiteConfig("preview", map[string]any{
+		"enabled": false,
+	})
+
+	result, err := p.RenderTerraformProviders("preview")
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+
+	result, err = p.RenderTerraformResources("preview")
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+
+	// A component enabled in a disabled site still needs the provider.
+	p.SetSiteComponentConfig("preview", "my-component", map[string]any{
+		"enabled": true,
+	})
+	result, err = p.RenderTerraformProviders("preview")
+	assert.NoError(t, err)
+	assert.Contains(t, result, "labd/sentry")
+}
+
+func TestValidate(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token": "foobar",
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{})
+	p.SetSiteConfig("my-site", map[string]any{
+		"organization": "my-org",
+	})
+	p.SetSiteConfig("other-site", map[string]any{
+		"organization": "my-org",
+		"project":      "my-project",
+	})
+	p.SetSiteComponentConfig("other-site", "my-component", map[string]any{
+		"rate_limit_count": 10,
+	})
+	p.SetSiteComponentConfig("other-site", "disabled-component", map[string]any{
+		"enabled": false,
+	})
+	p.SetSiteComponentConfig("third-site", "my-component", map[string]any{
+		"project": "my-project",
+	})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+	p.SetComponentConfig("disabled-component", "abc123", map[string]any{})
+	p.SetComponentConfig("unused-component", "abc123", map[string]any{})
+
+	// Only the components registered for a site are validated, also when the
+	// site config is set after the components.
+	err := p.Validate()
+	assert.EqualError(t, err, "sites.my-site.components.my-component: mode managed requires project to be set unless create_project is enabled\n"+
+		"sites.other-site.components.my-component: rate_limit_count requires rate_limit_window to be set\n"+
+		"sites.third-site.components.my-component: mode managed requires organization to be set")
+
+	_, err = p.RenderTerraformProviders("my-site")
+	assert.ErrorContains(t, err, "invalid sentry config: sites.my-site.components.my-component")
+}
+
+func TestValidateInferredUnmanagedMode(t *testing.T) {
+	p := NewSentryPlugin()
+
+	// An unconfigured plugin only warns and passes an empty DSN.
+	p.SetGlobalConfig(map[string]any{})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	assert.NoError(t, p.Validate())
+	providers, err := p.RenderTerraformProviders("my-site")
+	assert.NoError(t, err)
+	assert.Empty(t, providers)
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Equal(t, `sentry_dsn = ""`, result.Variables)
+
+	// Once Sentry is configured without auth_token the DSN is required.
+	p.SetGlobalConfig(map[string]any{
+		"organization": "my-org",
+	})
+	p.SetSiteComponentConfig("my-site", "other-component", map[string]any{
+		"dsn": "https://sentry.io/123",
+	})
+	p.SetComponentConfig("other-component", "abc123", map[string]any{})
+
+	err = p.Validate()
+	assert.EqualError(t, err, "sites.my-site.components.my-component: mode unmanaged requires dsn to be set")
+}
+
+func TestValidateProjectsPerSite(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":   "foobar",
+		"organization": "my-org",
+	})
+	p.SetSiteComponentConfig("site-a", "x", map[string]any{
+		"project":        "project-x",
+		"create_project": true,
+		"team":           "my-team",
+	})
+	p.SetSiteComponentConfig("site-b", "y", map[string]any{
+		"project":        "project-y",
+		"create_project": true,
+		"team":           "my-team",
+	})
+	p.SetComponentConfig("x", "abc123", map[string]any{})
+	p.SetComponentConfig("y", "abc123", map[string]any{})
+
+	assert.NoError(t, p.Validate())
+}
+
+func TestRenderTerraformComponentWithProjectOwnerSite(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":         "foobar",
+		"organization":       "my-org",
+		"project":            "my-project",
+		"create_project":     true,
+		"team":               "my-team",
+		"project_owner_site": "my-site",
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{})
+	p.SetSiteComponentConfig("other-site", "my-component", map[string]any{})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Resources, `resource "sentry_project" "my-project" {`)
+
+	result, err = p.RenderTerraformComponent("other-site", "my-component")
+	assert.NoError(t, err)
+	assert.NotContains(t, result.Resources, `resource "sentry_project"`)
+	assert.Contains(t, result.Resources, `data "sentry_project" "my-project" {`)
+	assert.Contains(t, result.Resources, `project           = data.sentry_project.my-project.slug`)
+}
+
+func TestRenderTerraformComponentWithSharedProject(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":     "foobar",
+		"organization":   "my-org",
+		"project":        "my-project",
+		"create_project": true,
+		"team":           "my-team",
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{})
+	p.SetSiteComponentConfig("my-site", "other-component", map[string]any{})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+	p.SetComponentConfig("other-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Resources, `resource "sentry_project" "my-project" {`)
+
+	result, err = p.RenderTerraformComponent("my-site", "other-component")
+	assert.NoError(t, err)
+	assert.NotContains(t, result.Resources, `"sentry_project"`)
+	assert.Contains(t, result.Resources, `project           = sentry_project.my-project.slug`)
+}
+
+func TestValidateProjectWithoutOwnerSite(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":     "foobar",
+		"organization":   "my-org",
+		"project":        "my-project",
+		"create_project": true,
+		"team":           "my-team",
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{})
+	p.SetSiteComponentConfig("other-site", "my-component", map[string]any{})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	err := p.Validate()
+	assert.EqualError(t, err, "sites.my-site.components.my-component: project my-org/my-project is managed by sites my-site, other-site, which requires project_owner_site to be set\n"+
+		"sites.other-site.components.my-component: project my-org/my-project is managed by sites my-site, other-site, which requires project_owner_site to be set")
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":         "foobar",
+		"organization":       "my-org",
+		"project":            "my-project",
+		"create_project":     true,
+		"team":               "my-team",
+		"project_owner_site": "my-site",
+	})
+	assert.NoError(t, p.Validate())
+}
+
+func TestValidateTeamWithoutSite(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":   "foobar",
+		"organization": "my-org",
+		"project":      "my-project",
+		"teams": []any{
+			map[string]any{"slug": "backend"},
+		},
+	})
+	p.SetSiteConfig("eu-1", map[string]any{
+		"organization": "eu-org",
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{})
+	p.SetSiteComponentConfig("other-site", "my-component", map[string]any{})
+	p.SetSiteComponentConfig("eu-1", "my-component", map[string]any{})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	err := p.Validate()
+	assert.EqualError(t, err, "teams.backend: team backend is managed by sites my-site, other-site of organization my-org, which requires site to be set")
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":   "foobar",
+		"organization": "my-org",
+		"project":      "my-project",
+		"teams": []any{
+			map[string]any{"slug": "backend", "site": "my-site"},
+		},
+	})
+	assert.NoError(t, p.Validate())
+}
+
+func TestRenderTerraformComponentWithSharedProjectConflict(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":     "foobar",
+		"organization":   "my-org",
+		"project":        "my-project",
+		"create_project": true,
+		"team":           "my-team",
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{})
+	p.SetSiteComponentConfig("my-site", "other-component", map[string]any{
+		"platform": "python",
+	})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+	p.SetComponentConfig("other-component", "abc123", map[string]any{})
+
+	_, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+
+	_, err = p.RenderTerraformComponent("my-site", "other-component")
+	assert.EqualError(t, err, "invalid config for component other-component in site my-site: project my-project is shared with component my-component, which has different project settings")
+}
+
+func TestRenderTerraformComponentWithSharedProjectAlerts(t *testing.T) {
+	p := NewSentryPlugin()
+
+	err := p.SetGlobalConfig(map[string]any{
+		"auth_token":         "foobar",
+		"organization":       "my-org",
+		"project":            "my-project",
+		"project_owner_site": "my-site",
+		"alerts": []any{
+			map[string]any{"name": "New issue"},
+		},
+		"metric_alerts": []any{
+			map[string]any{
+				"name":        "Error rate",
+				"aggregate":   "count()",
+				"time_window": 60,
+				"triggers": []any{
+					map[string]any{"label": "critical", "alert_threshold": 300},
+				},
+			},
+		},
+	})
+	assert.NoError(t, err)
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{})
+	p.SetSiteComponentConfig("my-site", "other-component", map[string]any{})
+	p.SetSiteComponentConfig("other-site", "my-component", map[string]any{})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+	p.SetComponentConfig("other-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Resources, `resource "sentry_issue_alert" "my-project_new_issue"`)
+	assert.Contains(t, result.Resources, `resource "sentry_metric_alert" "my-project_error_rate"`)
+
+	// The alerts are rendered once per project, by the site owning it.
+	result, err = p.RenderTerraformComponent("my-site", "other-component")
+	assert.NoError(t, err)
+	assert.NotContains(t, result.Resources, "sentry_issue_alert")
+	assert.NotContains(t, result.Resources, "sentry_metric_alert")
+
+	result, err = p.RenderTerraformComponent("other-site", "my-component")
+	assert.NoError(t, err)
+	assert.NotContains(t, result.Resources, "sentry_issue_alert")
+	assert.NotContains(t, result.Resources, "sentry_metric_alert")
+}
+
+func TestRenderTerraformComponentWithSharedProjectInboundFilters(t *testing.T) {
+	p := NewSentryPlugin()
+
+	err := p.SetGlobalConfig(map[string]any{
+		"auth_token":   "foobar",
+		"organization": "my-org",
+		"project":      "my-project",
+		"inbound_filters": map[string]any{
+			"localhost": true,
+		},
+	})
+	assert.NoError(t, err)
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{})
+	p.SetSiteComponentConfig("my-site", "other-component", map[string]any{
+		"inbound_filters": map[string]any{
+			"web_crawlers": true,
+		},
+	})
+	p.SetSiteComponentConfig("my-site", "third-component", map[string]any{})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+	p.SetComponentConfig("other-component", "abc123", map[string]any{})
+	p.SetComponentConfig("third-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Resources, `resource "sentry_project_inbound_data_filter" "my-project_localhost"`)
+
+	result, err = p.RenderTerraformComponent("my-site", "third-component")
+	assert.NoError(t, err)
+	assert.NotContains(t, result.Resources, "sentry_project_inbound_data_filter")
+
+	_, err = p.RenderTerraformComponent("my-site", "other-component")
+	assert.ErrorContains(t, err, "project my-project is shared with component my-component, which has different project settings")
+}
+
+func TestRenderTerraformComponentWithKeyRotationRemovesOldKeys(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":   "foobar",
+		"organization": "my-org",
+		"project":      "my-project",
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+		"key_rotation": map[string]any{
+			"generation": 2,
+		},
+	})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.NotContains(t, result.Resources, `resource "sentry_key" "my-component" {`)
+	assert.Contains(t, result.Resources, `resource "sentry_key" "my-component_v1" {`)
+	assert.Contains(t, result.Resources, `resource "sentry_key" "my-component_v2" {`)
+
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+		"key_rotation": map[string]any{
+			"generation":       2,
+			"keep_generations": 0,
+		},
+	})
+
+	result, err = p.RenderTerraformComponent("my-site", "my-component")
+	assert.NoError(t, err)
+	assert.NotContains(t, result.Resources, `resource "sentry_key" "my-component_v1" {`)
+	assert.Contains(t, result.Resources, `resource "sentry_key" "my-component_v2" {`)
 }
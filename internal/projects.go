@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// sentryProject describes the Sentry project of a component.
+type sentryProject struct {
+	// Label is the label of the sentry_project resource or data source, and
+	// the prefix of the labels of the project settings.
+	Label string
+	// Slug is the slug of the project in Sentry.
+	Slug string
+	// Name is the name of the project when it is created by the plugin.
+	Name string
+	// Create is whether the project is created by the plugin.
+	Create bool
+	// Managed is whether the project and its settings are managed by the
+	// site. Projects owned by another site are only read.
+	Managed bool
+	// Render is false when the project is shared with another component of
+	// the site which already renders it.
+	Render bool
+}
+
+// reference returns the expression referring to the project. Projects created
+// by the plugin are referred to through the resource or data source, so
+// Terraform creates the project first.
+func (p sentryProject) reference() string {
+	switch {
+	case !p.Create:
+		return fmt.Sprintf("%q", p.Slug)
+	case p.Managed:
+		return fmt.Sprintf("sentry_project.%s.slug", p.Label)
+	default:
+		return fmt.Sprintf("data.sentry_project.%s.slug", p.Label)
+	}
+}
+
+// projectSettings holds the settings of a component which apply to its
+// project as a whole. Components sharing a project must agree on them, since
+// the project is only rendered once.
+type projectSettings struct {
+	Create          bool
+	Name            string
+	Platform        string
+	Team            string
+	InboundFilters  *InboundFilters
+	SpikeProtection *bool
+	Alerts          []IssueAlert
+	MetricAlerts    []MetricAlert
+}
+
+// sharedProject is the component rendering a project in a site, with the
+// project settings it renders.
+type sharedProject struct {
+	Component string
+	Settings  projectSettings
+}
+
+// projectSettings returns the settings applying to the project of the
+// component.
+func (c *SiteComponentConfig) projectSettings() projectSettings {
+	var settings projectSettings
+	if c.createProject() {
+		settings.Create = true
+		settings.Name = c.ProjectName
+		settings.Platform = c.Platform
+		settings.Team = c.Team
+	}
+	settings.InboundFilters = c.InboundFilters
+	settings.SpikeProtection = c.SpikeProtection
+	settings.Alerts = c.Alerts
+	settings.MetricAlerts = c.MetricAlerts
+	return settings
+}
+
+// componentProject determines the project of a component. The project is
+// managed by the site set as project_owner_site, or by every site when no
+// owner is set. Within a site the project is rendered by the first component
+// using it.
+func (p *SentryPlugin) componentProject(site, component string, cfg SiteComponentConfig) (sentryProject, error) {
+	slug := cfg.projectSlug(component)
+	label := identifier(slug)
+	managed := cfg.ProjectOwnerSite == "" || cfg.ProjectOwnerSite == site
+
+	owner := fmt.Sprintf("%s/%s", site, label)
+	shared, ok := p.sharedProjects[owner]
+	if !ok {
+		shared = sharedProject{Component: component, Settings: cfg.projectSettings()}
+		p.sharedProjects[owner] = shared
+	}
+	if managed && shared.Component != component && !reflect.DeepEqual(shared.Settings, cfg.projectSettings()) {
+		return sentryProject{}, fmt.Errorf("project %s is shared with component %s, which has different project settings", slug, shared.Component)
+	}
+
+	name := cfg.ProjectName
+	if name == "" {
+		name = slug
+	}
+
+	return sentryProject{
+		Label:   label,
+		Slug:    slug,
+		Name:    name,
+		Create:  cfg.createProject(),
+		Managed: managed,
+		Render:  shared.Component == component,
+	}, nil
+}
@@ -0,0 +1,432 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// terraformJSON is a Terraform configuration in JSON syntax, as read by
+// Terraform from .tf.json files.
+type terraformJSON struct {
+	Terraform *terraformSettingsJSON    `json:"terraform,omitempty"`
+	Provider  map[string][]providerJSON `json:"provider,omitempty"`
+	Resource  map[string]map[string]any `json:"resource,omitempty"`
+	Data      map[string]map[string]any `json:"data,omitempty"`
+	Import    []importJSON              `json:"import,omitempty"`
+	Moved     []movedJSON               `json:"moved,omitempty"`
+}
+
+type terraformSettingsJSON struct {
+	RequiredProviders map[string]requiredProviderJSON `json:"required_providers"`
+}
+
+type requiredProviderJSON struct {
+	Source  string `json:"source"`
+	Version string `json:"version"`
+}
+
+type providerJSON struct {
+	Alias   string `json:"alias,omitempty"`
+	Token   string `json:"token,omitempty"`
+	BaseURL string `json:"base_url"`
+}
+
+type importJSON struct {
+	Provider string `json:"provider,omitempty"`
+	To       string `json:"to"`
+	ID       string `json:"id"`
+}
+
+type movedJSON struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type teamJSON struct {
+	Provider     string `json:"provider,omitempty"`
+	Organization string `json:"organization"`
+	Name         string `json:"name,omitempty"`
+	Slug         string `json:"slug"`
+}
+
+type projectJSON struct {
+	Provider     string              `json:"provider,omitempty"`
+	Organization string              `json:"organization"`
+	Teams        []string            `json:"teams"`
+	Name         string              `json:"name"`
+	Slug         string              `json:"slug"`
+	Platform     string              `json:"platform,omitempty"`
+	Filters      *projectFiltersJSON `json:"filters,omitempty"`
+}
+
+type projectLookupJSON struct {
+	Provider     string `json:"provider,omitempty"`
+	Organization string `json:"organization"`
+	Slug         string `json:"slug"`
+}
+
+type projectFiltersJSON struct {
+	ErrorMessages []string `json:"error_messages,omitempty"`
+	Releases      []string `json:"releases,omitempty"`
+}
+
+type releaseDeploymentJSON struct {
+	Provider     string   `json:"provider,omitempty"`
+	Organization string   `json:"organization"`
+	Version      string   `json:"version"`
+	Environment  string   `json:"environment"`
+	Projects     []string `json:"projects"`
+	DependsOn    []string `json:"depends_on"`
+}
+
+type keyJSON struct {
+	Provider        string `json:"provider,omitempty"`
+	Organization    string `json:"organization"`
+	Project         string `json:"project"`
+	ID              string `json:"id,omitempty"`
+	Name            string `json:"name,omitempty"`
+	RateLimitWindow *int   `json:"rate_limit_window,omitempty"`
+	RateLimitCount  *int   `json:"rate_limit_count,omitempty"`
+}
+
+type inboundFilterJSON struct {
+	Provider     string   `json:"provider,omitempty"`
+	Organization string   `json:"organization"`
+	Project      string   `json:"project"`
+	FilterID     string   `json:"filter_id"`
+	Active       bool     `json:"active"`
+	Subfilters   []string `json:"subfilters,omitempty"`
+}
+
+type spikeProtectionJSON struct {
+	Provider     string `json:"provider,omitempty"`
+	Organization string `json:"organization"`
+	Project      string `json:"project"`
+	Enabled      bool   `json:"enabled"`
+}
+
+type issueAlertJSON struct {
+	Provider     string `json:"provider,omitempty"`
+	Organization string `json:"organization"`
+	Project      string `json:"project"`
+	Name         string `json:"name"`
+	ActionMatch  string `json:"action_match"`
+	FilterMatch  string `json:"filter_match"`
+	Frequency    int    `json:"frequency"`
+	Environment  string `json:"environment,omitempty"`
+	Conditions   any    `json:"conditions,omitempty"`
+	Filters      any    `json:"filters,omitempty"`
+	Actions      any    `json:"actions,omitempty"`
+}
+
+type metricAlertJSON struct {
+	Provider         string                   `json:"provider,omitempty"`
+	Organization     string                   `json:"organization"`
+	Project          string                   `json:"project"`
+	Name             string                   `json:"name"`
+	Dataset          string                   `json:"dataset"`
+	Query            string                   `json:"query"`
+	Aggregate        string                   `json:"aggregate"`
+	TimeWindow       int                      `json:"time_window"`
+	ThresholdType    int                      `json:"threshold_type"`
+	ResolveThreshold *float64                 `json:"resolve_threshold,omitempty"`
+	Environment      string                   `json:"environment,omitempty"`
+	Trigger          []metricAlertTriggerJSON `json:"trigger,omitempty"`
+}
+
+type metricAlertTriggerJSON struct {
+	Label            string                  `json:"label"`
+	AlertThreshold   float64                 `json:"alert_threshold"`
+	ThresholdType    *int                    `json:"threshold_type,omitempty"`
+	ResolveThreshold *float64                `json:"resolve_threshold,omitempty"`
+	Action           []metricAlertActionJSON `json:"action,omitempty"`
+}
+
+type metricAlertActionJSON struct {
+	Type             string `json:"type"`
+	TargetType       string `json:"target_type"`
+	TargetIdentifier string `json:"target_identifier"`
+	IntegrationID    *int   `json:"integration_id,omitempty"`
+}
+
+func newTerraformJSON() *terraformJSON {
+	return &terraformJSON{
+		Provider: map[string][]providerJSON{},
+		Resource: map[string]map[string]any{},
+		Data:     map[string]map[string]any{},
+	}
+}
+
+func (t *terraformJSON) addResource(resourceType, label string, body any) {
+	if t.Resource[resourceType] == nil {
+		t.Resource[resourceType] = map[string]any{}
+	}
+	t.Resource[resourceType][label] = body
+}
+
+func (t *terraformJSON) addData(dataType, label string, body any) {
+	if t.Data[dataType] == nil {
+		t.Data[dataType] = map[string]any{}
+	}
+	t.Data[dataType][label] = body
+}
+
+// addProvider adds the required provider and the provider configuration of a
+// site.
+func (t *terraformJSON) addProvider(backend providerBackend, version string, provider providerResource) {
+	t.Terraform = &terraformSettingsJSON{
+		RequiredProviders: map[string]requiredProviderJSON{
+			"sentry": {Source: backend.source, Version: version},
+		},
+	}
+
+	baseURL := provider.URL
+	if baseURL == "" {
+		baseURL = "https://sentry.io/api/"
+	}
+	t.Provider["sentry"] = append(t.Provider["sentry"], providerJSON{
+		Alias:   provider.Alias,
+		Token:   provider.Token,
+		BaseURL: baseURL,
+	})
+}
+
+func (t *terraformJSON) addTeams(teams []teamResource, globalCfg GlobalConfig, providerAlias string) {
+	provider := providerReference(providerAlias)
+	for _, team := range teams {
+		if !team.Managed {
+			t.addData("sentry_team", team.Label, teamJSON{
+				Provider:     provider,
+				Organization: globalCfg.Organization,
+				Slug:         team.Slug,
+			})
+			continue
+		}
+
+		name := team.Name
+		if name == "" {
+			name = team.Slug
+		}
+		t.addResource("sentry_team", team.Label, teamJSON{
+			Provider:     provider,
+			Organization: globalCfg.Organization,
+			Name:         name,
+			Slug:         team.Slug,
+		})
+	}
+}
+
+func (t *terraformJSON) addLookup(lookup lookupResource) {
+	key := keyJSON{
+		Provider:     providerReference(lookup.ProviderAlias),
+		Organization: lookup.Global.Organization,
+		Project:      lookup.Config.Project,
+		ID:           lookup.Config.KeyID,
+		Name:         lookup.Config.KeyName,
+	}
+	t.addData("sentry_key", lookup.Label, key)
+}
+
+// addComponentResources adds the same resources as the resources template of
+// the provider backend.
+func (t *terraformJSON) addComponentResources(r componentResources) {
+	provider := providerReference(r.ProviderAlias)
+	organization := r.Global.Organization
+	project := jsonExpression(r.Project)
+
+	switch {
+	case r.CreateProject && r.RenderProject && r.ProjectManaged:
+		resource := projectJSON{
+			Provider:     provider,
+			Organization: organization,
+			Teams:        []string{jsonExpression(r.Team)},
+			Name:         r.ProjectName,
+			Slug:         r.ProjectSlug,
+			Platform:     r.Config.Platform,
+		}
+		if r.ProjectFilters {
+			resource.Filters = &projectFiltersJSON{
+				ErrorMessages: r.Config.InboundFilters.ErrorMessages,
+				Releases:      r.Config.InboundFilters.Releases,
+			}
+		}
+		t.addResource("sentry_project", r.ProjectLabel, resource)
+	case r.CreateProject && r.RenderProject:
+		t.addData("sentry_project", r.ProjectLabel, projectLookupJSON{
+			Provider:     provider,
+			Organization: organization,
+			Slug:         r.ProjectSlug,
+		})
+	}
+
+	if r.TrackDeployments {
+		t.addResource("sentry_release_deployment", r.ComponentLabel, releaseDeploymentJSON{
+			Provider:     provider,
+			Organization: organization,
+			Version:      r.Release,
+			Environment:  r.Environment,
+			Projects:     []string{project},
+			DependsOn:    []string{fmt.Sprintf("module.%s", r.ComponentLabel)},
+		})
+	}
+
+	switch {
+	case r.Key.Render && r.Key.Managed:
+		for _, generation := range r.Key.Generations() {
+			t.addResource("sentry_key", generation.Label, keyJSON{
+				Provider:        provider,
+				Organization:    organization,
+				Project:         project,
+				Name:            generation.Name,
+				RateLimitWindow: r.Config.RateLimitWindow,
+				RateLimitCount:  r.Config.RateLimitCount,
+			})
+		}
+		if r.Config.KeyID != "" {
+			if r.ImportKeyLabel != "" {
+				t.Import = append(t.Import, importJSON{
+					Provider: provider,
+					To:       fmt.Sprintf("sentry_key.%s", r.ImportKeyLabel),
+					ID:       fmt.Sprintf("%s/%s/%s", organization, r.ProjectSlug, r.Config.KeyID),
+				})
+			}
+			if r.CreateProject && r.RenderProject && r.ProjectManaged {
+				t.Import = append(t.Import, importJSON{
+					Provider: provider,
+					To:       fmt.Sprintf("sentry_project.%s", r.ProjectLabel),
+					ID:       fmt.Sprintf("%s/%s", organization, r.ProjectSlug),
+				})
+			}
+		}
+	case r.Key.Render:
+		t.addData("sentry_key", r.Key.Label, keyJSON{
+			Provider:     provider,
+			Organization: organization,
+			Project:      project,
+			Name:         r.Key.Name,
+		})
+	}
+
+	for _, filter := range r.InboundFilters {
+		t.addResource("sentry_project_inbound_data_filter", filter.Label, inboundFilterJSON{
+			Provider:     provider,
+			Organization: organization,
+			Project:      project,
+			FilterID:     filter.FilterID,
+			Active:       filter.Active,
+			Subfilters:   filter.Subfilters,
+		})
+	}
+
+	if r.SpikeProtection != nil {
+		t.addResource("sentry_project_spike_protection", r.ProjectLabel, spikeProtectionJSON{
+			Provider:     provider,
+			Organization: organization,
+			Project:      project,
+			Enabled:      *r.SpikeProtection,
+		})
+	}
+
+	for _, alert := range r.Alerts {
+		resource := issueAlertJSON{
+			Provider:     provider,
+			Organization: organization,
+			Project:      project,
+			Name:         alert.Name,
+			ActionMatch:  alert.ActionMatch,
+			FilterMatch:  alert.FilterMatch,
+			Frequency:    alert.Frequency,
+			Environment:  alert.Environment,
+		}
+		if resource.ActionMatch == "" {
+			resource.ActionMatch = "any"
+		}
+		if resource.FilterMatch == "" {
+			resource.FilterMatch = "any"
+		}
+		if resource.Frequency == 0 {
+			resource.Frequency = 30
+		}
+		if r.backend.encodedAlertRules {
+			resource.Conditions = optionalString(alert.ConditionsJSON)
+			resource.Filters = optionalString(alert.FiltersJSON)
+			resource.Actions = optionalString(alert.ActionsJSON)
+		} else {
+			resource.Conditions = optionalRules(alert.Conditions)
+			resource.Filters = optionalRules(alert.Filters)
+			resource.Actions = optionalRules(alert.Actions)
+		}
+		t.addResource("sentry_issue_alert", alert.Label, resource)
+	}
+
+	for _, alert := range r.MetricAlerts {
+		resource := metricAlertJSON{
+			Provider:         provider,
+			Organization:     organization,
+			Project:          project,
+			Name:             alert.Name,
+			Dataset:          alert.Dataset,
+			Query:            alert.Query,
+			Aggregate:        alert.Aggregate,
+			TimeWindow:       alert.TimeWindow,
+			ThresholdType:    alert.ThresholdType,
+			ResolveThreshold: alert.ResolveThreshold,
+			Environment:      alert.Environment,
+		}
+		for _, trigger := range alert.Triggers {
+			triggerJSON := metricAlertTriggerJSON{
+				Label:            trigger.Label,
+				AlertThreshold:   trigger.AlertThreshold,
+				ThresholdType:    trigger.ThresholdType,
+				ResolveThreshold: trigger.ResolveThreshold,
+			}
+			for _, action := range trigger.Actions {
+				triggerJSON.Action = append(triggerJSON.Action, metricAlertActionJSON{
+					Type:             action.Type,
+					TargetType:       action.TargetType,
+					TargetIdentifier: action.TargetIdentifier,
+					IntegrationID:    action.IntegrationID,
+				})
+			}
+			resource.Trigger = append(resource.Trigger, triggerJSON)
+		}
+		t.addResource("sentry_metric_alert", alert.Label, resource)
+	}
+
+	for _, moved := range r.Moved {
+		t.Moved = append(t.Moved, movedJSON{From: moved.From, To: moved.To})
+	}
+}
+
+// providerReference returns the provider meta-argument for resources of a site
+// with a provider alias.
+func providerReference(alias string) string {
+	if alias == "" {
+		return ""
+	}
+	return fmt.Sprintf("sentry.%s", alias)
+}
+
+// jsonExpression converts an HCL expression as passed to the templates into
+// JSON syntax. Quoted strings become plain strings and references become
+// interpolations.
+func jsonExpression(expr string) string {
+	if value, err := strconv.Unquote(expr); err == nil {
+		return value
+	}
+	return fmt.Sprintf("${%s}", expr)
+}
+
+func optionalString(value string) any {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+func optionalRules(rules []map[string]any) any {
+	if rules == nil {
+		return nil
+	}
+	return rules
+}
@@ -0,0 +1,279 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	hcljson "github.com/hashicorp/hcl/v2/json"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderTerraformJSON(t *testing.T) {
+	p := NewSentryPlugin()
+	p.Configure("test", "")
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":     "foobar",
+		"organization":   "my-org",
+		"project":        "my-project",
+		"create_project": true,
+		"team":           "backend",
+		"teams": []any{
+			map[string]any{"slug": "backend"},
+		},
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+		"rate_limit_window": 60,
+		"rate_limit_count":  100,
+		"spike_protection":  true,
+	})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{
+		"previous_names": []any{"old-component"},
+	})
+
+	result, err := p.RenderTerraformJSON("my-site", []string{"my-component"})
+	assert.NoError(t, err)
+
+	_, diags := hcljson.Parse(result, "sentry.tf.json")
+	assert.False(t, diags.HasErrors(), diags.Error())
+
+	var doc map[string]any
+	assert.NoError(t, json.Unmarshal(result, &doc))
+
+	assert.Equal(t, map[string]any{
+		"source":  "labd/sentry",
+		"version": "~> 1.0.2",
+	}, doc["terraform"].(map[string]any)["required_providers"].(map[string]any)["sentry"])
+
+	resources := doc["resource"].(map[string]any)
+	assert.Equal(t, map[string]any{
+		"organization":      "my-org",
+		"project":           "${sentry_project.my-project.slug}",
+		"name":              "test-my-site-my-component",
+		"rate_limit_window": float64(60),
+		"rate_limit_count":  float64(100),
+	}, resources["sentry_key"].(map[string]any)["my-component"])
+	assert.Equal(t, map[string]any{
+		"organization": "my-org",
+		"teams":        []any{"${sentry_team.team_backend.slug}"},
+		"name":         "my-project",
+		"slug":         "my-project",
+	}, resources["sentry_project"].(map[string]any)["my-project"])
+	assert.Equal(t, []any{"module.my-component"},
+		resources["sentry_release_deployment"].(map[string]any)["my-component"].(map[string]any)["depends_on"])
+	assert.Contains(t, resources["sentry_team"], "team_backend")
+	assert.Contains(t, resources["sentry_project_spike_protection"], "my-project")
+
+	assert.Contains(t, doc["moved"], map[string]any{
+		"from": "sentry_key.old-component",
+		"to":   "sentry_key.my-component",
+	})
+}
+
+func TestRenderTerraformJSONLookup(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"mode":         "lookup",
+		"organization": "my-org",
+		"project":      "my-project",
+		"key_name":     "production",
+	})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformJSON("my-site", []string{"my-component"})
+	assert.NoError(t, err)
+
+	var doc map[string]any
+	assert.NoError(t, json.Unmarshal(result, &doc))
+	assert.Equal(t, map[string]any{
+		"organization": "my-org",
+		"project":      "my-project",
+		"name":         "production",
+	}, doc["data"].(map[string]any)["sentry_key"].(map[string]any)["my-component"])
+	assert.NotContains(t, doc, "resource")
+}
+
+func TestRenderTerraformJSONSanitizesDependsOn(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":   "foobar",
+		"organization": "my-org",
+		"project":      "my-project",
+	})
+	p.SetComponentConfig("1st.component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformJSON("my-site", []string{"1st.component"})
+	assert.NoError(t, err)
+
+	var doc map[string]any
+	assert.NoError(t, json.Unmarshal(result, &doc))
+
+	resources := doc["resource"].(map[string]any)
+	assert.Equal(t, []any{"module._1st_component"},
+		resources["sentry_release_deployment"].(map[string]any)["_1st_component"].(map[string]any)["depends_on"])
+}
+
+func TestRenderTerraformJSONEncodedAlertRules(t *testing.T) {
+	p := NewSentryPlugin()
+
+	p.SetGlobalConfig(map[string]any{
+		"auth_token":      "foobar",
+		"organization":    "my-org",
+		"project":         "my-project",
+		"provider_source": "jianyuan/sentry",
+	})
+	p.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+		"alerts": []any{
+			map[string]any{
+				"name":       "Errors",
+				"conditions": []any{map[string]any{"id": "condition"}},
+				"actions":    []any{map[string]any{"id": "action"}},
+			},
+		},
+	})
+	p.SetComponentConfig("my-component", "abc123", map[string]any{})
+
+	result, err := p.RenderTerraformJSON("my-site", []string{"my-component"})
+	assert.NoError(t, err)
+
+	var doc map[string]any
+	assert.NoError(t, json.Unmarshal(result, &doc))
+	alert := doc["resource"].(map[string]any)["sentry_issue_alert"].(map[string]any)["my-project_errors"].(map[string]any)
+	assert.Equal(t, `[{"id":"condition"}]`, alert["conditions"])
+	assert.Equal(t, `[{"id":"action"}]`, alert["actions"])
+	assert.NotContains(t, alert, "filters")
+}
+
+func TestJSONExpression(t *testing.T) {
+	assert.Equal(t, "my-project", jsonExpression(`"my-project"`))
+	assert.Equal(t, "${sentry_project.my-component.slug}", jsonExpression("sentry_project.my-component.slug"))
+}
+
+// parityPlugin returns a plugin with components using every kind of resource
+// the plugin renders.
+func parityPlugin(t *testing.T, providerSource string) *SentryPlugin {
+	p := NewSentryPlugin()
+	p.Configure("test", "")
+
+	assert.NoError(t, p.SetGlobalConfig(map[string]any{
+		"auth_token":      "foobar",
+		"organization":    "my-org",
+		"provider_source": providerSource,
+		"teams": []any{
+			map[string]any{"slug": "backend"},
+		},
+	}))
+	assert.NoError(t, p.SetSiteComponentConfig("my-site", "shop", map[string]any{
+		"project":          "shop",
+		"create_project":   true,
+		"team":             "backend",
+		"spike_protection": true,
+		"inbound_filters": map[string]any{
+			"browser_extensions": true,
+			"legacy_browsers":    []any{"ie_pre_9"},
+		},
+		"key_rotation": map[string]any{
+			"generation": 1,
+		},
+		"alerts": []any{
+			map[string]any{
+				"name":       "New issues",
+				"conditions": []any{map[string]any{"id": "sentry.rules.conditions.first_seen_event.FirstSeenEventCondition"}},
+				"actions":    []any{map[string]any{"id": "sentry.mail.actions.NotifyEmailAction"}},
+			},
+		},
+		"metric_alerts": []any{
+			map[string]any{
+				"name":        "Error rate",
+				"aggregate":   "count()",
+				"query":       "event.type:error",
+				"time_window": 60,
+				"triggers": []any{
+					map[string]any{
+						"label":           "critical",
+						"alert_threshold": 300,
+					},
+				},
+			},
+		},
+	}))
+	assert.NoError(t, p.SetSiteComponentConfig("my-site", "checkout", map[string]any{
+		"project": "checkout",
+		"key_id":  "abcdef",
+	}))
+	assert.NoError(t, p.SetSiteComponentConfig("my-site", "legacy", map[string]any{
+		"mode":     "lookup",
+		"project":  "legacy",
+		"key_name": "legacy",
+	}))
+	assert.NoError(t, p.SetComponentConfig("shop", "abc123", map[string]any{
+		"previous_names": []any{"store"},
+	}))
+	assert.NoError(t, p.SetComponentConfig("checkout", "abc123", map[string]any{}))
+	assert.NoError(t, p.SetComponentConfig("legacy", "abc123", map[string]any{}))
+	return p
+}
+
+// TestRenderTerraformParity checks that the HCL and JSON output describe the
+// same resources, data sources, moved and import blocks.
+func TestRenderTerraformParity(t *testing.T) {
+	components := []string{"shop", "checkout", "legacy"}
+
+	for _, providerSource := range []string{providerSourceLabd, providerSourceJianyuan} {
+		t.Run(providerSource, func(t *testing.T) {
+			src, err := parityPlugin(t, providerSource).renderTerraformHCL("my-site", components)
+			assert.NoError(t, err)
+			file, diags := hclsyntax.ParseConfig(src, "sentry.tf", hcl.InitialPos)
+			assert.False(t, diags.HasErrors(), diags.Error())
+
+			var fromHCL []string
+			for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+				switch block.Type {
+				case "resource", "data":
+					fromHCL = append(fromHCL, strings.Join(append([]string{block.Type}, block.Labels...), "."))
+				case "moved", "import":
+					to := block.Body.Attributes["to"].Expr.Range().SliceBytes(src)
+					fromHCL = append(fromHCL, fmt.Sprintf("%s.%s", block.Type, to))
+				}
+			}
+
+			result, err := parityPlugin(t, providerSource).RenderTerraformJSON("my-site", components)
+			assert.NoError(t, err)
+			var doc struct {
+				Resource map[string]map[string]any `json:"resource"`
+				Data     map[string]map[string]any `json:"data"`
+				Moved    []map[string]string       `json:"moved"`
+				Import   []map[string]string       `json:"import"`
+			}
+			assert.NoError(t, json.Unmarshal(result, &doc))
+
+			var fromJSON []string
+			for blockType, blocks := range map[string]map[string]map[string]any{"resource": doc.Resource, "data": doc.Data} {
+				for resourceType, resources := range blocks {
+					for label := range resources {
+						fromJSON = append(fromJSON, fmt.Sprintf("%s.%s.%s", blockType, resourceType, label))
+					}
+				}
+			}
+			for _, block := range doc.Moved {
+				fromJSON = append(fromJSON, fmt.Sprintf("moved.%s", block["to"]))
+			}
+			for _, block := range doc.Import {
+				fromJSON = append(fromJSON, fmt.Sprintf("import.%s", block["to"]))
+			}
+
+			assert.ElementsMatch(t, fromHCL, fromJSON)
+			assert.Contains(t, fromHCL, "resource.sentry_project.shop")
+			assert.Contains(t, fromHCL, "resource.sentry_key.shop_v1")
+			assert.Contains(t, fromHCL, "data.sentry_key.legacy")
+			assert.Contains(t, fromHCL, "import.sentry_key.checkout")
+			assert.Contains(t, fromHCL, "moved.sentry_key.shop")
+		})
+	}
+}